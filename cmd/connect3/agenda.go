@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/agenda"
+	"github.com/N3moAhead/connect3/internal/cadence"
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/store"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// agendaItem shows one entry of the agenda
+type agendaItem struct {
+	entry agenda.Entry
+	today time.Time
+}
+
+func (i agendaItem) Title() string { return i.entry.Person.Name + ": " + i.entry.Text }
+func (i agendaItem) Description() string {
+	return i.entry.Section(i.today) + ", " + describeDue(i.entry, i.today)
+}
+func (i agendaItem) FilterValue() string { return i.entry.Person.Name }
+
+// describeDue says when an entry is due, e.g. "3 days ago (2024-01-01), reminder"
+func describeDue(e agenda.Entry, today time.Time) string {
+	date := e.Date.Format(config.DATE_LAYOUT)
+	switch days := e.Days(today); {
+	case days < -1:
+		date = fmt.Sprintf("%d days ago (%s)", -days, date)
+	case days == -1:
+		date = "yesterday (" + date + ")"
+	case days == 0:
+		date = "today"
+	case days == 1:
+		date = "tomorrow (" + date + ")"
+	default:
+		date = e.Date.Format("Monday") + " (" + date + ")"
+	}
+	return date + ", " + e.Kind
+}
+
+func newAgendaList() list.Model {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Agenda"
+	l.SetFilteringEnabled(false)
+	l.DisableQuitKeybindings()
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "Open")),
+			key.NewBinding(key.WithKeys("x"), key.WithHelp("x", "Done")),
+			key.NewBinding(key.WithKeys("s"), key.WithHelp("s", fmt.Sprintf("Snooze %d Day", config.REMINDER_SNOOZE_DAYS))),
+			key.NewBinding(key.WithKeys("D"), key.WithHelp("D", "Delete Reminder")),
+			key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "Back")),
+		}
+	}
+	return l
+}
+
+// refreshAgendaList shows what is overdue and due this week
+func (m *model) refreshAgendaList() {
+	today := cadence.Today(time.Now())
+	entries := agenda.Build(m.db, today, config.AGENDA_DAYS)
+	items := make([]list.Item, len(entries))
+	overdue := 0
+	for i, e := range entries {
+		items[i] = agendaItem{entry: e, today: today}
+		if e.Days(today) < 0 {
+			overdue++
+		}
+	}
+	m.listAgenda.SetItems(items)
+	m.listAgenda.Title = fmt.Sprintf("Agenda (%d overdue, %d ahead)", overdue, len(entries)-overdue)
+}
+
+func (m model) updateAgenda(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc", "backspace":
+			m.state = viewListPeople
+			return m, nil
+		case "enter":
+			if i, ok := m.listAgenda.SelectedItem().(agendaItem); ok {
+				p := i.entry.Person
+				m.selectedPerson = &p
+				m.backTo = viewAgenda
+				m.state = viewDetail
+				m.refreshRelationList()
+				m.refreshHistory()
+			}
+			return m, nil
+		case "x", "s":
+			if i, ok := m.listAgenda.SelectedItem().(agendaItem); ok {
+				m.completeOrSnooze(i.entry, msg.String() == "s")
+			}
+			return m, nil
+		case "D":
+			if i, ok := m.listAgenda.SelectedItem().(agendaItem); ok {
+				if i.entry.Kind != agenda.KindReminder {
+					m.notice = "Only reminders can be deleted here."
+					return m, nil
+				}
+				m.do("Delete reminder for "+i.entry.Person.Name, func(tx store.Tx) error { return tx.DeleteReminder(i.entry.ID) })
+			}
+			return m, nil
+		case "u":
+			m.undoRedo(false)
+			return m, nil
+		case "ctrl+r":
+			m.undoRedo(true)
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.listAgenda, cmd = m.listAgenda.Update(msg)
+	return m, cmd
+}
+
+// completeOrSnooze marks e as done or pushes it back to config.REMINDER_SNOOZE_DAYS
+// after today, never earlier than it already is. A done follow-up loses its date,
+// birthdays come back every year by themselves.
+func (m *model) completeOrSnooze(e agenda.Entry, snooze bool) {
+	today := cadence.Today(time.Now())
+	until := today.AddDate(0, 0, config.REMINDER_SNOOZE_DAYS)
+	switch e.Kind {
+	case agenda.KindReminder:
+		m.do("Update reminder for "+e.Person.Name, func(tx store.Tx) error {
+			r, err := tx.GetReminder(e.ID)
+			if err != nil {
+				return err
+			}
+			if snooze {
+				r.Snooze(today, config.REMINDER_SNOOZE_DAYS)
+			} else {
+				r.Complete(today)
+			}
+			return tx.UpdateReminder(r)
+		})
+	case agenda.KindFollowUp:
+		m.do("Update follow-up with "+e.Person.Name, func(tx store.Tx) error {
+			in, err := tx.GetInteraction(e.ID)
+			if err != nil {
+				return err
+			}
+			if !snooze {
+				in.FollowUp = ""
+			} else if date, err := interaction.ParseDate(in.FollowUp); err != nil || date.Before(until) {
+				in.FollowUp = until.Format(config.DATE_LAYOUT)
+			}
+			return tx.UpdateInteraction(in)
+		})
+	default:
+		m.notice = "Birthdays come back every year, there is nothing to complete or snooze."
+	}
+}
@@ -0,0 +1,578 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/agenda"
+	"github.com/N3moAhead/connect3/internal/backup"
+	"github.com/N3moAhead/connect3/internal/cadence"
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/export"
+	"github.com/N3moAhead/connect3/internal/graph"
+	"github.com/N3moAhead/connect3/internal/migration"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/store"
+	"github.com/N3moAhead/connect3/internal/undo"
+)
+
+func usage() {
+	out := flag.CommandLine.Output()
+	fmt.Fprintf(out, "Usage: c3 [--db path] [command]\n\n")
+	fmt.Fprintf(out, "Without a command the TUI is started.\n\n")
+	fmt.Fprintf(out, "Commands:\n")
+	fmt.Fprintf(out, "  import <file.json>   Copy a json database into the sqlite database given by --db\n")
+	fmt.Fprintf(out, "  recover [out.json]   Salvage people, relations, interactions and reminders from a broken json database\n")
+	fmt.Fprintf(out, "  backup list          List the backups of the database\n")
+	fmt.Fprintf(out, "  backup create        Take a backup now\n")
+	fmt.Fprintf(out, "  backup restore <id>  Replace the database with a backup\n")
+	fmt.Fprintf(out, "  migrate [--dry-run] [--to <version>] [--force]\n")
+	fmt.Fprintf(out, "                       Bring the json database up to date or to an older version,\n")
+	fmt.Fprintf(out, "                       --dry-run shows what that would change, --force drops data to downgrade\n")
+	fmt.Fprintf(out, "  doctor [--fix]       Check the database for broken references and values, --fix repairs them\n")
+	fmt.Fprintf(out, "  agenda [--days n]    Print the reminders, birthdays and follow-ups that are due\n")
+	fmt.Fprintf(out, "  path <from> <to>     Show how two people are connected, by name or id\n")
+	fmt.Fprintf(out, "  suggest-intros [--min n] [--limit n]\n")
+	fmt.Fprintf(out, "                       List people who should meet, both know someone well but not each other\n")
+	fmt.Fprintf(out, "  stats [--tag]        Show hubs, brokers, bridges and communities of the network,\n")
+	fmt.Fprintf(out, "                       --tag writes them to everyone as %s tags\n", autoTagPrefix)
+	fmt.Fprintf(out, "  export [--format dot|graphml|gexf] [--tag t] [--out file]\n")
+	fmt.Fprintf(out, "                       Write the network for Graphviz or Gephi, only people tagged t with --tag\n")
+	fmt.Fprintf(out, "  encrypt              Encrypt the json database and its backups with a passphrase\n")
+	fmt.Fprintf(out, "  decrypt              Turn an encrypted json database back into plain json\n\n")
+	fmt.Fprintf(out, "The passphrase of an encrypted database is asked for on start,\n")
+	fmt.Fprintf(out, "or taken from the %s environment variable.\n\n", PASSPHRASE_ENV)
+	fmt.Fprintf(out, "Flags:\n")
+	flag.PrintDefaults()
+}
+
+// env is what every subcommand gets to work with
+type env struct {
+	dbPath       string
+	readOnly     bool // Another instance holds the lock
+	backupPolicy backup.Policy
+}
+
+// writable fails if another instance holds the lock
+func (e env) writable() error {
+	if e.readOnly {
+		return fmt.Errorf("%s is open in another c3 instance", e.dbPath)
+	}
+	return nil
+}
+
+func runCommand(e env, name string, args []string) error {
+	dbPath := e.dbPath
+	switch name {
+	case "import":
+		if err := e.writable(); err != nil {
+			return err
+		}
+		if len(args) != 1 {
+			return fmt.Errorf("usage: c3 --db <file.db> import <file.json>")
+		}
+		if !store.IsSQLite(dbPath) {
+			return fmt.Errorf("import needs a sqlite database as --db (got %s)", dbPath)
+		}
+		if err := importJSON(dbPath, args[0]); err != nil {
+			return err
+		}
+		fmt.Printf("Imported %s into %s\n", args[0], dbPath)
+		return nil
+	case "recover":
+		if err := unlockDatabase(dbPath); err != nil {
+			return err
+		}
+		return runRecover(dbPath, args)
+	case "backup":
+		return runBackup(e, args)
+	case "migrate":
+		return runMigrate(e, args)
+	case "doctor":
+		return runDoctor(e, args)
+	case "agenda":
+		return runAgenda(e, args)
+	case "path":
+		return runPath(e, args)
+	case "suggest-intros":
+		return runSuggestIntros(e, args)
+	case "stats":
+		return runStats(e, args)
+	case "export":
+		return runExport(e, args)
+	case "encrypt":
+		return runEncrypt(e)
+	case "decrypt":
+		return runDecrypt(e)
+	}
+	flag.Usage()
+	return fmt.Errorf("unknown command %q", name)
+}
+
+// importJSON copies the json file into the sqlite database. Older files are
+// migrated on the way, the file itself is left as it is. An encrypted file
+// asks for its passphrase first.
+func importJSON(dbPath, jsonPath string) error {
+	if _, err := os.Stat(jsonPath); err != nil {
+		return err
+	}
+	if err := unlockDatabase(jsonPath); err != nil {
+		return err
+	}
+	return store.ImportJSONFile(jsonPath, dbPath)
+}
+
+// runMigrate runs the pending migrations, or walks the chain back down with --to.
+// With --dry-run it only shows the steps and what they change, the file is not touched.
+// A downgrade that drops data only runs with --force.
+func runMigrate(e env, args []string) error {
+	fs := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Show the steps and the changes without writing anything")
+	to := fs.String("to", config.DB_FORMAT_VERSION, "Version to migrate to, older versions revert migrations")
+	force := fs.Bool("force", false, "Downgrade even if that drops data the older version does not know")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	target, err := migration.ParseVersion(*to)
+	if err != nil {
+		return err
+	}
+	if store.IsSQLite(e.dbPath) {
+		return fmt.Errorf("migrations only apply to json databases, sqlite is always up to date")
+	}
+	if !*dryRun {
+		if err := e.writable(); err != nil {
+			return err
+		}
+	}
+	if err := unlockDatabase(e.dbPath); err != nil {
+		return err
+	}
+	p, err := migration.PrepareTo(e.dbPath, target)
+	if err != nil {
+		return err
+	}
+	if p == nil {
+		return fmt.Errorf("%s does not exist yet", e.dbPath)
+	}
+	if len(p.Steps) == 0 {
+		fmt.Printf("%s is already at version %s\n", e.dbPath, p.From)
+		return nil
+	}
+	if !*dryRun {
+		if len(p.Losses) > 0 {
+			printLosses(p.Losses)
+			if !*force {
+				return fmt.Errorf("version %s can not keep this data, run again with --force to drop it", target)
+			}
+		}
+		if err := migration.MigrateTo(e.dbPath, target); err != nil {
+			return err
+		}
+		if p.Down {
+			fmt.Printf("%s is now at version %s. Starting this c3 again upgrades it back.\n", e.dbPath, target)
+		}
+		return nil
+	}
+
+	fmt.Printf("%s is at version %s, these migrations would run:\n", e.dbPath, p.From)
+	for _, m := range p.Steps {
+		if p.Down {
+			fmt.Printf("  %s -> %s (revert)\n", m.ToVersion, m.FromVersion)
+		} else {
+			fmt.Printf("  %s -> %s\n", m.FromVersion, m.ToVersion)
+		}
+	}
+	lines, err := migration.Diff(p.Before, p.After)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nChanges (%d):\n", len(lines))
+	for _, line := range lines {
+		fmt.Println("  " + line)
+	}
+	if len(p.Losses) > 0 {
+		fmt.Println()
+		printLosses(p.Losses)
+	}
+	return nil
+}
+
+// printLosses lists what a downgrade drops
+func printLosses(losses []migration.Loss) {
+	fmt.Println("Downgrading drops data the older version does not know:")
+	for _, l := range losses {
+		fmt.Println("  " + l.String())
+	}
+}
+
+// runDoctor lists the problems db.Validate finds and repairs them with --fix
+func runDoctor(e env, args []string) error {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fix := fs.Bool("fix", false, "Repair what can be repaired safely")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *fix {
+		if err := e.writable(); err != nil {
+			return err
+		}
+	}
+	if err := unlockDatabase(e.dbPath); err != nil {
+		return err
+	}
+	if _, err := os.Stat(e.dbPath); err != nil {
+		return err
+	}
+	st, err := store.OpenReadOnly(e.dbPath)
+	if err != nil {
+		return err
+	}
+	database, err := st.Snapshot()
+	st.Close()
+	if err != nil {
+		return err
+	}
+	problems := db.Validate(database)
+	if len(problems) == 0 {
+		fmt.Printf("No problems found in %s\n", e.dbPath)
+		return nil
+	}
+	fmt.Printf("%d problems found in %s:\n", len(problems), e.dbPath)
+	for _, p := range problems {
+		fmt.Printf("  %s\n", p)
+	}
+	if !*fix {
+		if _, fixable := db.Fix(database); len(fixable) > 0 {
+			fmt.Printf("\nRepair what can be repaired with: c3 --db %s doctor --fix\n", e.dbPath)
+		} else {
+			fmt.Printf("\nThese have to be fixed by hand.\n")
+		}
+		return nil
+	}
+
+	b, err := createBackup(e.dbPath, "pre-doctor")
+	if err != nil {
+		return err
+	}
+	fixed, left, err := store.Repair(e.dbPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nFixed %d problems:\n", len(fixed))
+	for _, p := range fixed {
+		fmt.Printf("  %s\n", p)
+	}
+	if b != nil {
+		fmt.Printf("The database before the repair is backup %s\n", b.ID)
+	}
+	if len(left) > 0 {
+		fmt.Printf("\n%d problems need to be fixed by hand:\n", len(left))
+		for _, p := range left {
+			fmt.Printf("  %s\n", p)
+		}
+	}
+	return nil
+}
+
+// runAgenda prints the agenda of the TUI grouped by section, e.g. for a login script
+func runAgenda(e env, args []string) error {
+	fs := flag.NewFlagSet("agenda", flag.ContinueOnError)
+	days := fs.Int("days", config.AGENDA_DAYS, "How many days ahead to look")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	database, err := readDatabase(e)
+	if err != nil {
+		return err
+	}
+	today := cadence.Today(time.Now())
+	entries := agenda.Build(database, today, *days)
+	if len(entries) == 0 {
+		fmt.Printf("Nothing due in the next %d days\n", *days)
+		return nil
+	}
+	section := ""
+	for _, entry := range entries {
+		if s := entry.Section(today); s != section {
+			if section != "" {
+				fmt.Println()
+			}
+			section = s
+			fmt.Println(section + ":")
+		}
+		fmt.Printf("  %s  %s: %s\n", entry.Date.Format(config.DATE_LAYOUT), entry.Person.Name, entry.Text)
+	}
+	return nil
+}
+
+// runPath prints the path with the fewest hops and the one over the strongest ties
+func runPath(e env, args []string) error {
+	if len(args) != 2 {
+		return fmt.Errorf("usage: c3 path <from> <to>")
+	}
+	database, err := readDatabase(e)
+	if err != nil {
+		return err
+	}
+	from, err := lookupPerson(database.People, args[0])
+	if err != nil {
+		return err
+	}
+	to, err := lookupPerson(database.People, args[1])
+	if err != nil {
+		return err
+	}
+	res := findPaths(graph.New(database.Relations), from, to)
+	if !res.found {
+		return fmt.Errorf("%s and %s are not connected", from.Name, to.Name)
+	}
+	titles, paths := pathSections(res)
+	for i, p := range paths {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Println(titles[i] + ":")
+		for _, line := range describeHops(database.People, p) {
+			fmt.Println("  " + line)
+		}
+	}
+	return nil
+}
+
+// runSuggestIntros prints the introductions worth making, strongest first
+func runSuggestIntros(e env, args []string) error {
+	fs := flag.NewFlagSet("suggest-intros", flag.ContinueOnError)
+	minStrength := fs.Int("min", config.INTRO_MIN_STRENGTH, "Weakest relation to the common contact that counts")
+	limit := fs.Int("limit", 20, "Most suggestions to list, 0 for all")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	database, err := readDatabase(e)
+	if err != nil {
+		return err
+	}
+	intros := graph.New(database.Relations).Intros(personIDs(database.People), *minStrength)
+	if len(intros) == 0 {
+		fmt.Printf("No introductions to suggest, nobody with relations of strength %d or more has two contacts who do not know each other\n", *minStrength)
+		return nil
+	}
+	if *limit > 0 && len(intros) > *limit {
+		fmt.Printf("The %d strongest of %d suggestions:\n", *limit, len(intros))
+		intros = intros[:*limit]
+	}
+	for _, in := range intros {
+		fmt.Printf("  %s\n", describeIntro(database.People, in, ""))
+	}
+	return nil
+}
+
+// runStats prints the network stats and with --tag writes them back as auto tags
+func runStats(e env, args []string) error {
+	fs := flag.NewFlagSet("stats", flag.ContinueOnError)
+	tag := fs.Bool("tag", false, "Replace the "+autoTagPrefix+" tags of everyone with the results")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *tag {
+		if err := e.writable(); err != nil {
+			return err
+		}
+	}
+	database, err := readDatabase(e)
+	if err != nil {
+		return err
+	}
+	stats := analyze(database)
+	for _, line := range statsReport(database, stats) {
+		fmt.Println(line)
+	}
+	if !*tag {
+		return nil
+	}
+
+	b, err := createBackup(e.dbPath, "pre-stats")
+	if err != nil {
+		return err
+	}
+	st, err := store.Open(e.dbPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	tags := autoTags(stats)
+	changed := 0
+	err = undo.Do(st, "Write auto tags", func(tx store.Tx) (err error) {
+		changed, err = writeAutoTags(tx, tags)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	fmt.Printf("\nUpdated the %s tags of %d people\n", autoTagPrefix, changed)
+	if b != nil {
+		fmt.Printf("The database before is backup %s, or press u in c3 to undo\n", b.ID)
+	}
+	return nil
+}
+
+// runExport writes the people and relations as a graph file, to stdout without --out
+func runExport(e env, args []string) error {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", export.FormatDOT, "One of "+strings.Join(export.Formats, ", "))
+	tag := fs.String("tag", "", "Only export people with this tag and the relations between them")
+	out := fs.String("out", "", "File to write to instead of stdout")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if !slices.Contains(export.Formats, *format) {
+		return fmt.Errorf("unknown format %q, use one of %s", *format, strings.Join(export.Formats, ", "))
+	}
+	database, err := readDatabase(e)
+	if err != nil {
+		return err
+	}
+	network := export.Select(database, *tag)
+	if *out == "" {
+		return export.Write(os.Stdout, *format, network)
+	}
+	f, err := os.Create(*out)
+	if err != nil {
+		return err
+	}
+	if err := export.Write(f, *format, network); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Printf("Exported %d people and %d relations to %s\n", len(network.People), len(network.Relations), *out)
+	return nil
+}
+
+// readDatabase takes a snapshot of the database for the commands that only read it
+func readDatabase(e env) (db.Database, error) {
+	if err := unlockDatabase(e.dbPath); err != nil {
+		return db.Database{}, err
+	}
+	if _, err := os.Stat(e.dbPath); err != nil {
+		return db.Database{}, err
+	}
+	st, err := store.OpenReadOnly(e.dbPath)
+	if err != nil {
+		return db.Database{}, err
+	}
+	defer st.Close()
+	return st.Snapshot()
+}
+
+// lookupPerson finds someone by id or, ignoring case, by name
+func lookupPerson(people []person.Person, arg string) (person.Person, error) {
+	if p, ok := findPerson(people, arg); ok {
+		return p, nil
+	}
+	id, err := findByName(people, arg)
+	if err != nil {
+		return person.Person{}, err
+	}
+	p, _ := findPerson(people, id)
+	return p, nil
+}
+
+// runRecover writes everything readable from a broken database into a new file
+func runRecover(dbPath string, args []string) error {
+	if store.IsSQLite(dbPath) {
+		return fmt.Errorf("recover only works on json databases")
+	}
+	outPath := strings.TrimSuffix(dbPath, filepath.Ext(dbPath)) + ".recovered.json"
+	if len(args) > 0 {
+		outPath = args[0]
+	}
+	if _, err := os.Stat(outPath); err == nil {
+		return fmt.Errorf("%s already exists, not overwriting it", outPath)
+	}
+	database, err := store.Recover(dbPath, outPath)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Recovered %d people, %d relations, %d interactions and %d reminders into %s\n",
+		len(database.People), len(database.Relations), len(database.Interactions), len(database.Reminders), outPath)
+	fmt.Printf("%s was not touched. Check the result with: c3 --db %s\n", dbPath, outPath)
+	return nil
+}
+
+func runBackup(e env, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("usage: c3 backup list|create|restore <id>")
+	}
+	switch args[0] {
+	case "list":
+		backups, err := backup.List(e.dbPath)
+		if err != nil {
+			return err
+		}
+		if len(backups) == 0 {
+			fmt.Println("No backups in", backup.Dir(e.dbPath))
+			return nil
+		}
+		for _, b := range backups {
+			fmt.Printf("%s  %s  %8d bytes  %s\n", b.ID, b.Time.Format("2006-01-02 15:04:05"), b.Size, b.Label)
+		}
+		return nil
+
+	case "create":
+		if store.IsSQLite(e.dbPath) {
+			// The running instance may still have changes in the journal
+			if err := e.writable(); err != nil {
+				return err
+			}
+		}
+		b, err := createBackup(e.dbPath, "")
+		if err != nil {
+			return err
+		}
+		if b == nil {
+			return fmt.Errorf("%s does not exist yet", e.dbPath)
+		}
+		fmt.Printf("Created backup %s\n", b.ID)
+		return backup.Prune(e.dbPath, e.backupPolicy)
+
+	case "restore":
+		if err := e.writable(); err != nil {
+			return err
+		}
+		if len(args) != 2 {
+			return fmt.Errorf("usage: c3 backup restore <id>")
+		}
+		b, err := backup.Restore(e.dbPath, args[1])
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Restored backup %s from %s\n", b.ID, b.Time.Format("2006-01-02 15:04:05"))
+		return nil
+	}
+	return fmt.Errorf("unknown backup command %q", args[0])
+}
+
+// createBackup takes a backup the way the backend needs it
+func createBackup(dbPath, label string) (*backup.Backup, error) {
+	if !store.IsSQLite(dbPath) {
+		return backup.Create(dbPath, label)
+	}
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, nil
+	}
+	st, err := store.OpenSQLite(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	defer st.Close()
+	return backup.CreateWith(dbPath, label, st.BackupTo)
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Fields of the person form in tab order. The custom fields follow
+// from fieldCustom on, notes come last and enter there saves the form.
+const (
+	fieldName = iota
+	fieldCompany
+	fieldJobTitle
+	fieldBirthday
+	fieldCadence
+	fieldEmails
+	fieldPhones
+	fieldAddresses
+	fieldURLs
+	fieldCustom
+)
+
+// fieldNotes is the index of the notes in the person form
+func (m model) fieldNotes() int {
+	return fieldCustom + len(m.inputCustom)
+}
+
+// detailFields are the fields in model.inputDetails, from fieldCompany to fieldURLs
+var detailFields = []struct {
+	label       string
+	placeholder string
+	kind        string // Contact kind for the multi-valued fields
+}{
+	{"Company", "Company", ""},
+	{"Job Title", "Job Title", ""},
+	{"Birthday", "1990-12-31, or --12-31 without the year", ""},
+	{"Keep in Touch", "Every how many days, e.g. 30", ""},
+	{"Emails", "work: ada@example.com; home: ada@home.org", person.KindEmail},
+	{"Phones", "mobile: +49 170 1234567", person.KindPhone},
+	{"Addresses", "home: Main Street 1, Berlin", person.KindAddress},
+	{"Links", "github: https://github.com/ada", person.KindURL},
+}
+
+func newDetailInputs() []textinput.Model {
+	inputs := make([]textinput.Model, len(detailFields))
+	for i, f := range detailFields {
+		inputs[i] = textinput.New()
+		inputs[i].Placeholder = f.placeholder
+		// Without a width textinput only shows the first rune of the placeholder
+		inputs[i].Width = 50
+	}
+	return inputs
+}
+
+// formatEntries renders entries as "label: value; label: value" for editing
+func formatEntries(entries []person.Entry) string {
+	parts := make([]string, len(entries))
+	for i, e := range entries {
+		if e.Label == "" {
+			parts[i] = e.Value
+		} else {
+			parts[i] = e.Label + ": " + e.Value
+		}
+	}
+	return strings.Join(parts, "; ")
+}
+
+// parseEntries reads what formatEntries writes. The label ends at the
+// first ": ", so values like https://... keep their colon.
+func parseEntries(s string) []person.Entry {
+	entries := []person.Entry{}
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		e := person.Entry{Value: part}
+		if label, value, ok := strings.Cut(part, ": "); ok {
+			e = person.Entry{Label: strings.TrimSpace(label), Value: strings.TrimSpace(value)}
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// blurForm takes the cursor out of the person form
+func (m *model) blurForm() {
+	m.inputName.Blur()
+	m.inputNotes.Blur()
+	for i := range m.inputDetails {
+		m.inputDetails[i].Blur()
+	}
+	for i := range m.inputCustom {
+		m.inputCustom[i].Blur()
+	}
+}
+
+// focusForm moves the cursor of the person form to field
+func (m *model) focusForm(field int) {
+	m.formFocus = field
+	m.blurForm()
+	switch {
+	case field == fieldName:
+		m.inputName.Focus()
+	case field == m.fieldNotes():
+		m.inputNotes.Focus()
+	case field >= fieldCustom:
+		m.inputCustom[field-fieldCustom].Focus()
+	default:
+		m.inputDetails[field-fieldCompany].Focus()
+	}
+}
+
+// fillPersonForm puts p into the person form, with an input for every custom field
+func (m *model) fillPersonForm(p person.Person) {
+	m.customFields = m.db.Fields
+	m.inputCustom = newCustomInputs(m.customFields)
+	for i, f := range m.customFields {
+		m.inputCustom[i].SetValue(p.Custom[f.Name])
+	}
+	m.inputName.SetValue(p.Name)
+	m.inputNotes.SetValue(p.Notes)
+	cadence := ""
+	if p.Cadence > 0 {
+		cadence = strconv.Itoa(p.Cadence)
+	}
+	values := []string{p.Company, p.JobTitle, p.Birthday, cadence}
+	for _, f := range detailFields[len(values):] {
+		values = append(values, formatEntries(*p.Entries(f.kind)))
+	}
+	for i, v := range values {
+		m.inputDetails[i].SetValue(v)
+	}
+}
+
+// readPersonForm copies the person form into p
+func (m *model) readPersonForm(p *person.Person) error {
+	birthday := strings.TrimSpace(m.inputDetails[fieldBirthday-fieldCompany].Value())
+	if birthday != "" {
+		if _, _, _, err := person.ParseBirthday(birthday); err != nil {
+			return err
+		}
+	}
+	cadence := 0
+	if s := strings.TrimSpace(m.inputDetails[fieldCadence-fieldCompany].Value()); s != "" {
+		var err error
+		if cadence, err = strconv.Atoi(s); err != nil || cadence < 0 {
+			return fmt.Errorf("keep in touch %q is not a number of days", s)
+		}
+	}
+	if err := m.readCustom(p); err != nil {
+		return err
+	}
+	p.Name = m.inputName.Value()
+	p.Notes = m.inputNotes.Value()
+	p.Tags = m.tempTags
+	p.Company = strings.TrimSpace(m.inputDetails[fieldCompany-fieldCompany].Value())
+	p.JobTitle = strings.TrimSpace(m.inputDetails[fieldJobTitle-fieldCompany].Value())
+	p.Birthday = birthday
+	p.Cadence = cadence
+	for i, f := range detailFields {
+		if f.kind != "" {
+			*p.Entries(f.kind) = parseEntries(m.inputDetails[i].Value())
+		}
+	}
+	return nil
+}
+
+// personFormDetails renders the detail inputs of the person form
+func (m model) personFormDetails() string {
+	s := ""
+	for i, f := range detailFields {
+		s += f.label + ":\n" + m.inputDetails[i].View() + "\n\n"
+	}
+	return s
+}
+
+// contactView renders the contact details of p for the detail view
+func contactView(p person.Person) string {
+	s := ""
+	work := p.JobTitle
+	if p.Company != "" {
+		if work != "" {
+			work += " at "
+		}
+		work += p.Company
+	}
+	if work != "" {
+		s += work + "\n"
+	}
+	if p.Birthday != "" {
+		s += infoStyle.Render("Birthday: ") + p.Birthday + "\n"
+	}
+	for _, f := range detailFields {
+		if f.kind == "" {
+			continue
+		}
+		for _, e := range *p.Entries(f.kind) {
+			label := f.label
+			if e.Label != "" {
+				label += " (" + e.Label + ")"
+			}
+			s += infoStyle.Render(label+": ") + e.Value + "\n"
+		}
+	}
+	if s == "" {
+		return ""
+	}
+	return lipgloss.NewStyle().MarginBottom(1).Render(strings.TrimSuffix(s, "\n")) + "\n"
+}
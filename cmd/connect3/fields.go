@@ -0,0 +1,452 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/store"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Inputs of the field form in model.inputField
+const (
+	fieldFormName = iota
+	fieldFormType
+	fieldFormOptions
+)
+
+// fieldItem shows a field definition in the fields list
+type fieldItem struct{ def db.FieldDef }
+
+func (i fieldItem) Title() string { return i.def.Name }
+func (i fieldItem) Description() string {
+	if i.def.Type == db.FieldEnum {
+		return i.def.Type + ": " + strings.Join(i.def.Options, ", ")
+	}
+	return i.def.Type
+}
+func (i fieldItem) FilterValue() string { return i.def.Name }
+
+func newFieldList() list.Model {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Custom Fields"
+	l.SetFilteringEnabled(false)
+	l.DisableQuitKeybindings()
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "New Field")),
+			key.NewBinding(key.WithKeys("e"), key.WithHelp("e", "Edit")),
+			key.NewBinding(key.WithKeys("d"), key.WithHelp("d", "Delete")),
+			key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "Back")),
+		}
+	}
+	return l
+}
+
+func newFieldInputs() []textinput.Model {
+	placeholders := []string{
+		"Name, e.g. Timezone",
+		strings.Join(db.FieldTypes, ", "),
+		"Options of an enum: red, green, blue",
+	}
+	inputs := make([]textinput.Model, len(placeholders))
+	for i, p := range placeholders {
+		inputs[i] = textinput.New()
+		inputs[i].Placeholder = p
+		inputs[i].Width = 50
+	}
+	return inputs
+}
+
+// customPlaceholder tells what a value of f looks like
+func customPlaceholder(f db.FieldDef) string {
+	switch f.Type {
+	case db.FieldNumber:
+		return "A number"
+	case db.FieldDate:
+		return "2024-12-31"
+	case db.FieldBool:
+		return "yes or no"
+	case db.FieldEnum:
+		return strings.Join(f.Options, ", ")
+	}
+	return f.Name
+}
+
+// newCustomInputs makes one input per defined field, in the order of the definitions
+func newCustomInputs(defs []db.FieldDef) []textinput.Model {
+	inputs := make([]textinput.Model, len(defs))
+	for i, f := range defs {
+		inputs[i] = textinput.New()
+		inputs[i].Placeholder = customPlaceholder(f)
+		inputs[i].Width = 50
+	}
+	return inputs
+}
+
+// readCustom copies the custom inputs into p. Values of fields that are not
+// in the form, gone or added since it was opened, are kept, an empty input removes the value.
+func (m *model) readCustom(p *person.Person) error {
+	custom := map[string]string{}
+	for name, value := range p.Custom {
+		if _, ok := db.FindField(m.customFields, name); !ok {
+			custom[name] = value
+		}
+	}
+	for i, f := range m.customFields {
+		value, err := f.Normalize(m.inputCustom[i].Value())
+		if err != nil {
+			return err
+		}
+		if value != "" {
+			custom[f.Name] = value
+		}
+	}
+	p.Custom = custom
+	return nil
+}
+
+// customFormView renders the custom inputs of the person form
+func (m model) customFormView() string {
+	s := ""
+	for i, f := range m.customFields {
+		s += f.Name + ":\n" + m.inputCustom[i].View() + "\n\n"
+	}
+	return s
+}
+
+// customView renders the custom values of p for the detail view
+func customView(defs []db.FieldDef, p person.Person) string {
+	lines := []string{}
+	for _, f := range defs {
+		value, ok := p.Custom[f.Name]
+		if !ok || value == "" {
+			continue
+		}
+		if f.Type == db.FieldBool {
+			value = map[string]string{"true": "yes", "false": "no"}[value]
+		}
+		lines = append(lines, infoStyle.Render(f.Name+": ")+value)
+	}
+	if len(lines) == 0 {
+		return ""
+	}
+	return lipgloss.NewStyle().MarginBottom(1).Render(strings.Join(lines, "\n")) + "\n"
+}
+
+// --- FIELDS VIEW ---
+
+func (m *model) refreshFieldList() {
+	items := make([]list.Item, len(m.db.Fields))
+	for i, f := range m.db.Fields {
+		items[i] = fieldItem{def: f}
+	}
+	m.listFields.SetItems(items)
+}
+
+// openFieldForm starts editing f, or creating a new field if f is nil
+func (m *model) openFieldForm(f *db.FieldDef) {
+	m.editingField = f
+	values := []string{"", db.FieldText, ""}
+	if f != nil {
+		values = []string{f.Name, f.Type, strings.Join(f.Options, ", ")}
+	}
+	for i, v := range values {
+		m.inputField[i].SetValue(v)
+	}
+	m.state = viewFieldForm
+	// Fields are looked up by name, so an existing field keeps it
+	if f != nil {
+		m.focusFieldForm(fieldFormType)
+	} else {
+		m.focusFieldForm(fieldFormName)
+	}
+}
+
+func (m *model) focusFieldForm(i int) {
+	m.fieldFocus = i
+	for j := range m.inputField {
+		m.inputField[j].Blur()
+	}
+	m.inputField[i].Focus()
+}
+
+func (m model) updateFields(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc", "backspace":
+			m.state = viewListPeople
+			return m, nil
+		case "n":
+			m.openFieldForm(nil)
+			return m, nil
+		case "e", "enter":
+			if i, ok := m.listFields.SelectedItem().(fieldItem); ok {
+				m.openFieldForm(&i.def)
+			}
+			return m, nil
+		case "d":
+			if i, ok := m.listFields.SelectedItem().(fieldItem); ok {
+				name := i.def.Name
+				if m.do("Delete field "+name, func(tx store.Tx) error { return tx.DeleteField(name) }) {
+					m.notice = "Deleted " + name + ", people keep their values and get them back if the field returns."
+				}
+			}
+			return m, nil
+		case "u":
+			m.undoRedo(false)
+			return m, nil
+		case "ctrl+r":
+			m.undoRedo(true)
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.listFields, cmd = m.listFields.Update(msg)
+	return m, cmd
+}
+
+func (m model) updateFieldForm(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		first := fieldFormName
+		if m.editingField != nil {
+			first = fieldFormType
+		}
+		switch msg.String() {
+		case "esc":
+			m.conflict = ""
+			m.state = viewFields
+			return m, nil
+		case "tab", "shift+tab":
+			next := m.fieldFocus + 1
+			if msg.String() == "shift+tab" {
+				next = m.fieldFocus - 1
+			}
+			if next > fieldFormOptions {
+				next = first
+			} else if next < first {
+				next = fieldFormOptions
+			}
+			m.focusFieldForm(next)
+			return m, nil
+		case "enter":
+			if m.fieldFocus != fieldFormOptions {
+				m.focusFieldForm(m.fieldFocus + 1)
+				return m, nil
+			}
+			if err := m.saveFieldForm(); err != nil {
+				m.err = err
+				return m, nil
+			}
+			m.state = viewFields
+			return m, nil
+		}
+	}
+	cmds := make([]tea.Cmd, len(m.inputField))
+	for i := range m.inputField {
+		m.inputField[i], cmds[i] = m.inputField[i].Update(msg)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// saveFieldForm checks the field form and writes the field. A new type
+// is refused while people still have values that do not fit it.
+func (m *model) saveFieldForm() error {
+	f := db.FieldDef{
+		Name: strings.TrimSpace(m.inputField[fieldFormName].Value()),
+		Type: strings.ToLower(strings.TrimSpace(m.inputField[fieldFormType].Value())),
+	}
+	if f.Type == db.FieldEnum {
+		for _, o := range strings.Split(m.inputField[fieldFormOptions].Value(), ",") {
+			if o = strings.TrimSpace(o); o != "" {
+				f.Options = append(f.Options, o)
+			}
+		}
+	}
+	if err := f.Check(); err != nil {
+		return err
+	}
+
+	misfits := []string{}
+	for _, p := range m.db.People {
+		value, ok := p.Custom[f.Name]
+		if !ok {
+			continue
+		}
+		if normalized, err := f.Normalize(value); err != nil || normalized != value {
+			misfits = append(misfits, p.Name)
+		}
+	}
+	if len(misfits) > 0 {
+		return fmt.Errorf("the values of %s do not fit a %s field, change them first", strings.Join(misfits, ", "), f.Type)
+	}
+
+	if m.editingField != nil {
+		if !m.do("Edit field "+f.Name, func(tx store.Tx) error { return tx.UpdateField(f) }) {
+			return m.err
+		}
+		return nil
+	}
+	if _, ok := db.FindField(m.db.Fields, f.Name); ok {
+		return fmt.Errorf("there already is a field called %s", f.Name)
+	}
+	if !m.do("Create field "+f.Name, func(tx store.Tx) error { return tx.CreateField(f) }) {
+		return m.err
+	}
+	return nil
+}
+
+func (m model) fieldFormView() string {
+	title := "New Custom Field"
+	if m.editingField != nil {
+		title = "Edit " + m.editingField.Name
+	}
+	s := titleStyle.Render(title) + "\n\n"
+	if m.editingField == nil {
+		s += "Name:\n" + m.inputField[fieldFormName].View() + "\n\n"
+	}
+	s += "Type:\n" + m.inputField[fieldFormType].View() + "\n\n"
+	s += "Options (enum only):\n" + m.inputField[fieldFormOptions].View() + "\n\n"
+	return s + infoStyle.Render("Enter on Options to Save | Tab/Shift+Tab: Next/Previous Field | ESC: Back")
+}
+
+// --- PEOPLE FILTER ---
+
+// fieldCondition is one part of a people filter like "timezone=CET"
+type fieldCondition struct {
+	field db.FieldDef
+	op    string // One of filterOps, or "" for "has a value"
+	value string
+}
+
+// Longer operators first, so "!=" is not read as "="
+var filterOps = []string{"!=", "<=", ">=", "=", "<", ">", "~"}
+
+// parseFilter reads conditions separated by ";", e.g.
+// "timezone=CET; met at~berlin; age>30". A bare field name
+// matches everyone who has a value for it.
+func parseFilter(defs []db.FieldDef, s string) ([]fieldCondition, error) {
+	conds := []fieldCondition{}
+	for _, part := range strings.Split(s, ";") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		c := fieldCondition{}
+		name := part
+		for _, op := range filterOps {
+			if before, after, ok := strings.Cut(part, op); ok {
+				name, c.op, c.value = strings.TrimSpace(before), op, strings.TrimSpace(after)
+				break
+			}
+		}
+		f, ok := db.FindField(defs, name)
+		if !ok {
+			return nil, fmt.Errorf("there is no field called %q", name)
+		}
+		c.field = f
+		if c.op != "" && c.op != "~" {
+			value, err := f.Normalize(c.value)
+			if err != nil {
+				return nil, err
+			}
+			c.value = value
+		}
+		conds = append(conds, c)
+	}
+	return conds, nil
+}
+
+func (c fieldCondition) match(p person.Person) bool {
+	value, ok := p.Custom[c.field.Name]
+	if !ok || value == "" {
+		return c.op == "!="
+	}
+	cmp := c.field.Compare(value, c.value)
+	switch c.op {
+	case "":
+		return true
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "~":
+		return strings.Contains(strings.ToLower(value), strings.ToLower(c.value))
+	}
+	return false
+}
+
+// refreshPeopleList shows the people matching the field filter
+func (m *model) refreshPeopleList() {
+	people := []person.Person{}
+	for _, p := range m.db.People {
+		keep := true
+		for _, c := range m.peopleFilter {
+			keep = keep && c.match(p)
+		}
+		if keep {
+			people = append(people, p)
+		}
+	}
+	m.listPeople.SetItems(peopleToItems(people))
+	m.listPeople.Title = "Connect3"
+	if m.filterText != "" {
+		m.listPeople.Title += " [" + m.filterText + "]"
+	}
+}
+
+func (m model) updatePeopleFilter(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc":
+			m.state = viewListPeople
+			return m, nil
+		case "enter":
+			text := strings.TrimSpace(m.inputFilter.Value())
+			conds, err := parseFilter(m.db.Fields, text)
+			if err != nil {
+				m.err = err
+				return m, nil
+			}
+			m.err = nil
+			m.peopleFilter, m.filterText = conds, text
+			m.refreshPeopleList()
+			m.listPeople.ResetSelected()
+			m.state = viewListPeople
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.inputFilter, cmd = m.inputFilter.Update(msg)
+	return m, cmd
+}
+
+func (m model) peopleFilterView() string {
+	names := make([]string, len(m.db.Fields))
+	for i, f := range m.db.Fields {
+		names[i] = f.Name + " (" + f.Type + ")"
+	}
+	if len(names) == 0 {
+		names = []string{"none yet, press F in the people list to add some"}
+	}
+	return titleStyle.Render("Filter People by Custom Fields") + "\n\n" +
+		m.inputFilter.View() + "\n\n" +
+		infoStyle.Render("Operators: = != < <= > >= and ~ for contains, a bare name means has a value.\n"+
+			"Separate conditions with ;, leave empty to show everyone.") + "\n\n" +
+		infoStyle.Render("Fields: "+strings.Join(names, ", ")) + "\n\n" +
+		infoStyle.Render("Enter: Apply | ESC: Back")
+}
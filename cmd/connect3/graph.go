@@ -0,0 +1,395 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/graph"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// Most people the graph view places on each ring, the rest are counted below it
+const (
+	graphMaxFirst  = 16
+	graphMaxSecond = 24
+	graphNameWidth = 14
+)
+
+// graphNode is a person in the ego network of the graph view
+type graphNode struct {
+	person person.Person
+	hop    int     // 0 for the center, 1 for direct connections, 2 for theirs
+	x, y   float64 // Position on the canvas, set by layoutGraph
+}
+
+// strengthStyles color the edges like the icons of relation.RelationItem
+var strengthStyles = []lipgloss.Style{
+	lipgloss.NewStyle().Foreground(lipgloss.Color("250")),
+	lipgloss.NewStyle().Foreground(lipgloss.Color("39")),
+	lipgloss.NewStyle().Foreground(lipgloss.Color("42")),
+	lipgloss.NewStyle().Foreground(lipgloss.Color("220")).Bold(true),
+	lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true),
+}
+
+// edgeRunes are the horizontal, vertical, falling and rising strokes of
+// an edge for each strength, weak ties are dotted and strong ones heavy
+var edgeRunes = [][4]rune{
+	{'·', '·', '·', '·'},
+	{'┄', '┆', '╲', '╱'},
+	{'─', '│', '╲', '╱'},
+	{'━', '┃', '╲', '╱'},
+	{'━', '┃', '╲', '╱'},
+}
+
+// strengthIndex maps a strength to its style, out of range values are clamped
+func strengthIndex(strength int) int {
+	return graph.Clamp(strength) - db.MIN_STRENGTH
+}
+
+// egoNetwork collects everyone up to hops relations away from center, center first,
+// then ring by ring. The second ring is ordered by who it is reached through, so
+// people end up close to their connection. hidden counts who did not fit.
+func egoNetwork(database db.Database, ix *graph.Index, center string, hops int) (nodes []graphNode, hidden int) {
+	p, ok := findPerson(database.People, center)
+	if !ok {
+		return nil, 0
+	}
+	byName := func(ids []string) []person.Person {
+		people := []person.Person{}
+		for _, id := range ids {
+			if p, ok := findPerson(database.People, id); ok {
+				people = append(people, p)
+			}
+		}
+		slices.SortFunc(people, func(a, b person.Person) int { return strings.Compare(a.Name, b.Name) })
+		return people
+	}
+
+	seen := map[string]bool{center: true}
+	nodes = []graphNode{{person: p, hop: 0}}
+	first := []person.Person{}
+	for _, q := range byName(ix.Neighbors(center)) {
+		if seen[q.ID] {
+			continue
+		}
+		seen[q.ID] = true
+		if len(first) == graphMaxFirst {
+			hidden++
+			continue
+		}
+		first = append(first, q)
+		nodes = append(nodes, graphNode{person: q, hop: 1})
+	}
+	if hops < 2 {
+		return nodes, hidden
+	}
+	second := 0
+	for _, via := range first {
+		for _, q := range byName(ix.Neighbors(via.ID)) {
+			if seen[q.ID] {
+				continue
+			}
+			seen[q.ID] = true
+			if second == graphMaxSecond {
+				hidden++
+				continue
+			}
+			second++
+			nodes = append(nodes, graphNode{person: q, hop: 2})
+		}
+	}
+	return nodes, hidden
+}
+
+// layoutGraph puts the center in the middle and the rings around it on ellipses,
+// twice as wide as high since terminal cells are about twice as high as wide
+func layoutGraph(nodes []graphNode, width, height int) {
+	cx, cy := float64(width-1)/2, float64(height-1)/2
+	rx := max(cx-graphNameWidth/2-1, 1)
+	ry := max(cy-1, 1)
+	rings := map[int]int{}
+	for _, n := range nodes {
+		rings[n.hop]++
+	}
+	scale := map[int]float64{1: 1}
+	if rings[2] > 0 {
+		scale[1] = 0.5
+		scale[2] = 1
+	}
+	placed := map[int]int{}
+	for i := range nodes {
+		hop := nodes[i].hop
+		if hop == 0 {
+			nodes[i].x, nodes[i].y = cx, cy
+			continue
+		}
+		// Start at the top, the second ring half a step further so lines do not overlap
+		angle := 2*math.Pi*float64(placed[hop])/float64(rings[hop]) - math.Pi/2
+		if hop == 2 {
+			angle += math.Pi / float64(max(rings[1], 1))
+		}
+		placed[hop]++
+		nodes[i].x = cx + rx*scale[hop]*math.Cos(angle)
+		nodes[i].y = cy + ry*scale[hop]*math.Sin(angle)
+	}
+}
+
+// graphCell is one character of the rendered graph
+type graphCell struct {
+	r     rune
+	style *lipgloss.Style // nil for plain text
+}
+
+// renderGraph draws the relations between the nodes as lines and the names on top of them
+func renderGraph(nodes []graphNode, relations []relation.Relation, selected, width, height int) string {
+	canvas := make([][]graphCell, height)
+	for y := range canvas {
+		canvas[y] = make([]graphCell, width)
+		for x := range canvas[y] {
+			canvas[y][x] = graphCell{r: ' '}
+		}
+	}
+	index := map[string]int{}
+	for i, n := range nodes {
+		index[n.person.ID] = i
+	}
+	// Strong ties are drawn last so they stay visible where lines cross
+	relations = slices.Clone(relations)
+	slices.SortStableFunc(relations, func(a, b relation.Relation) int { return a.Strength - b.Strength })
+	for _, r := range relations {
+		from, okFrom := index[r.FromID]
+		to, okTo := index[r.ToID]
+		if !okFrom || !okTo || from == to {
+			continue
+		}
+		s := strengthIndex(r.Strength)
+		drawEdge(canvas, nodes[from], nodes[to], edgeRunes[s], &strengthStyles[s])
+	}
+
+	selectedStyle := lipgloss.NewStyle().Reverse(true)
+	for i, n := range nodes {
+		var style *lipgloss.Style
+		switch {
+		case i == selected:
+			style = &selectedStyle
+		case n.hop == 0:
+			style = &titleStyle
+		}
+		label := []rune(" " + truncate(n.person.Name, graphNameWidth-2) + " ")
+		y := int(math.Round(n.y))
+		x := min(max(int(math.Round(n.x))-len(label)/2, 0), max(width-len(label), 0))
+		for j, r := range label {
+			if y >= 0 && y < height && x+j < width {
+				canvas[y][x+j] = graphCell{r: r, style: style}
+			}
+		}
+	}
+
+	var b strings.Builder
+	for y, row := range canvas {
+		if y > 0 {
+			b.WriteString("\n")
+		}
+		// Runs of the same style are rendered together to keep the escape codes down
+		for start := 0; start < len(row); {
+			end := start
+			var run []rune
+			for end < len(row) && row[end].style == row[start].style {
+				run = append(run, row[end].r)
+				end++
+			}
+			if row[start].style == nil {
+				b.WriteString(string(run))
+			} else {
+				b.WriteString(row[start].style.Render(string(run)))
+			}
+			start = end
+		}
+	}
+	return b.String()
+}
+
+// drawEdge plots a straight line between two nodes, picking the stroke by the direction of each step
+func drawEdge(canvas [][]graphCell, a, b graphNode, strokes [4]rune, style *lipgloss.Style) {
+	x0, y0 := int(math.Round(a.x)), int(math.Round(a.y))
+	x1, y1 := int(math.Round(b.x)), int(math.Round(b.y))
+	dx, dy := abs(x1-x0), -abs(y1-y0)
+	sx, sy := sign(x1-x0), sign(y1-y0)
+	e := dx + dy
+	for x0 != x1 || y0 != y1 {
+		stepX, stepY := 0, 0
+		if 2*e >= dy {
+			e += dy
+			stepX = sx
+		}
+		if 2*e <= dx {
+			e += dx
+			stepY = sy
+		}
+		stroke := strokes[0]
+		switch {
+		case stepX == 0:
+			stroke = strokes[1]
+		case stepY == 0:
+		case stepX == stepY:
+			stroke = strokes[2]
+		default:
+			stroke = strokes[3]
+		}
+		x0 += stepX
+		y0 += stepY
+		if y0 >= 0 && y0 < len(canvas) && x0 >= 0 && x0 < len(canvas[y0]) {
+			canvas[y0][x0] = graphCell{r: stroke, style: style}
+		}
+	}
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sign(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// truncate shortens s to n runes, marking the cut with an ellipsis
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+// openGraph shows the ego network of the selected person. Coming from a detail
+// view that was opened in the graph, the graph keeps its original way out,
+// otherwise ESC would bounce between the two forever.
+func (m *model) openGraph() {
+	m.graphCenter = m.selectedPerson.ID
+	if m.backTo != viewGraph {
+		m.graphBackTo = m.backTo
+	}
+	m.graphSelected = 0
+	m.state = viewGraph
+	m.refreshGraph()
+}
+
+// refreshGraph rebuilds the network around the center and keeps the selected person selected
+func (m *model) refreshGraph() {
+	selectedID := ""
+	if m.graphSelected < len(m.graphNodes) {
+		selectedID = m.graphNodes[m.graphSelected].person.ID
+	}
+	m.graphNodes, m.graphHidden = egoNetwork(m.db, m.network, m.graphCenter, m.graphHops)
+	m.graphSelected = 0
+	for i, n := range m.graphNodes {
+		if n.person.ID == selectedID {
+			m.graphSelected = i
+		}
+	}
+	layoutGraph(m.graphNodes, m.graphWidth, m.graphHeight)
+}
+
+func (m model) updateGraph(msg tea.Msg) (tea.Model, tea.Cmd) {
+	keyMsg, ok := msg.(tea.KeyMsg)
+	if !ok {
+		return m, nil
+	}
+	if len(m.graphNodes) == 0 {
+		// The center was deleted in the meantime
+		m.state = viewListPeople
+		return m, nil
+	}
+	switch keyMsg.String() {
+	case "esc", "backspace":
+		p := m.graphNodes[0].person
+		m.selectedPerson = &p
+		m.backTo = m.graphBackTo
+		m.state = viewDetail
+		m.refreshRelationList()
+		m.refreshHistory()
+	case "enter":
+		p := m.graphNodes[m.graphSelected].person
+		m.selectedPerson = &p
+		m.backTo = viewGraph
+		m.state = viewDetail
+		m.refreshRelationList()
+		m.refreshHistory()
+	case "c":
+		m.graphCenter = m.graphNodes[m.graphSelected].person.ID
+		m.refreshGraph()
+	case "1", "2":
+		m.graphHops = int(keyMsg.String()[0] - '0')
+		m.refreshGraph()
+	case "tab":
+		m.graphSelected = (m.graphSelected + 1) % len(m.graphNodes)
+	case "shift+tab":
+		m.graphSelected = (m.graphSelected + len(m.graphNodes) - 1) % len(m.graphNodes)
+	case "left", "h":
+		m.graphSelected = nearestNode(m.graphNodes, m.graphSelected, -1, 0)
+	case "right", "l":
+		m.graphSelected = nearestNode(m.graphNodes, m.graphSelected, 1, 0)
+	case "up", "k":
+		m.graphSelected = nearestNode(m.graphNodes, m.graphSelected, 0, -1)
+	case "down", "j":
+		m.graphSelected = nearestNode(m.graphNodes, m.graphSelected, 0, 1)
+	}
+	return m, nil
+}
+
+// nearestNode finds the node closest to the one at from in the direction dx, dy.
+// Nodes off to the side count as further away, from is kept if there is none.
+func nearestNode(nodes []graphNode, from int, dx, dy float64) int {
+	best, bestScore := from, math.Inf(1)
+	for i, n := range nodes {
+		// Halve x distances, they are twice as many cells as y distances
+		vx, vy := (n.x-nodes[from].x)/2, n.y-nodes[from].y
+		along := vx*dx + vy*dy
+		if i == from || along <= 0 {
+			continue
+		}
+		across := math.Abs(vx*dy - vy*dx)
+		if score := along + 2*across; score < bestScore {
+			best, bestScore = i, score
+		}
+	}
+	return best
+}
+
+func (m model) graphView() string {
+	if len(m.graphNodes) == 0 {
+		return "Error: No person selected."
+	}
+	center := m.graphNodes[0].person
+	s := titleStyle.Render(fmt.Sprintf("Network of %s (%d hops)", center.Name, m.graphHops)) + "\n"
+	legend := []string{}
+	for i, style := range strengthStyles {
+		legend = append(legend, style.Render(strings.Repeat(string(edgeRunes[i][0]), 3))+fmt.Sprintf(" %d", i+db.MIN_STRENGTH))
+	}
+	s += infoStyle.Render("Strength: ") + strings.Join(legend, "  ") + "\n"
+	if len(m.graphNodes) == 1 {
+		return s + "\n" + infoStyle.Render("(No connections yet - Press n in the detail view to add one)") + "\n\n" +
+			infoStyle.Render("ESC: Back")
+	}
+	s += renderGraph(m.graphNodes, m.db.Relations, m.graphSelected, m.graphWidth, m.graphHeight) + "\n"
+	if m.graphHidden > 0 {
+		s += infoStyle.Render(fmt.Sprintf("%d more people do not fit, press c on someone to look around them", m.graphHidden)) + "\n"
+	}
+	s += infoStyle.Render("Arrows/Tab: Move | Enter: Open | c: Center on Selected | 1/2: Hops | ESC: Back")
+	return s
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/store"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+// Inputs of the interaction form in model.inputInteraction, in tab order.
+// Enter on the last one saves the form.
+const (
+	interactionDate = iota
+	interactionKind
+	interactionWith
+	interactionSummary
+	interactionFollowUp
+)
+
+// detailInteractions is how many interactions the detail view shows
+const detailInteractions = 5
+
+func newInteractionInputs() []textinput.Model {
+	placeholders := []string{
+		"2024-12-31",
+		strings.Join(interaction.Kinds, ", "),
+		"Names of everyone else, e.g. Ada, Grace",
+		"What was it about?",
+		"Date to follow up, empty for none",
+	}
+	inputs := make([]textinput.Model, len(placeholders))
+	for i, p := range placeholders {
+		inputs[i] = textinput.New()
+		inputs[i].Placeholder = p
+		inputs[i].Width = 50
+	}
+	return inputs
+}
+
+// openInteractionForm starts logging an interaction with the selected person, today
+func (m *model) openInteractionForm() {
+	values := []string{time.Now().Format(config.DATE_LAYOUT), interaction.KindMeeting, "", "", ""}
+	for i, v := range values {
+		m.inputInteraction[i].SetValue(v)
+	}
+	m.state = viewInteractionForm
+	m.focusInteractionForm(interactionSummary)
+}
+
+func (m *model) focusInteractionForm(i int) {
+	m.interactionFocus = i
+	for j := range m.inputInteraction {
+		m.inputInteraction[j].Blur()
+	}
+	m.inputInteraction[i].Focus()
+}
+
+func (m model) updateInteractionForm(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		last := len(m.inputInteraction) - 1
+		switch msg.String() {
+		case "esc":
+			m.conflict = ""
+			m.state = viewDetail
+			return m, nil
+		case "tab":
+			m.focusInteractionForm((m.interactionFocus + 1) % (last + 1))
+			return m, nil
+		case "shift+tab":
+			m.focusInteractionForm((m.interactionFocus + last) % (last + 1))
+			return m, nil
+		case "enter":
+			if m.interactionFocus != last {
+				m.focusInteractionForm(m.interactionFocus + 1)
+				return m, nil
+			}
+			in, err := m.readInteractionForm()
+			if err == nil {
+				err = m.checkSelectedPerson()
+			}
+			if err != nil {
+				m.err = err
+				return m, nil
+			}
+			if !m.do("Log "+in.Kind+" with "+m.selectedPerson.Name, func(tx store.Tx) error { return tx.CreateInteraction(in) }) {
+				return m, nil
+			}
+			m.state = viewDetail
+			return m, nil
+		}
+	}
+	cmds := make([]tea.Cmd, len(m.inputInteraction))
+	for i := range m.inputInteraction {
+		m.inputInteraction[i], cmds[i] = m.inputInteraction[i].Update(msg)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+// readInteractionForm checks the interaction form and turns it into a new
+// interaction. The selected person always takes part.
+func (m model) readInteractionForm() (interaction.Interaction, error) {
+	in := interaction.Interaction{
+		ID:           uuid.New().String(),
+		Date:         strings.TrimSpace(m.inputInteraction[interactionDate].Value()),
+		Kind:         strings.ToLower(strings.TrimSpace(m.inputInteraction[interactionKind].Value())),
+		Participants: []string{m.selectedPerson.ID},
+		Summary:      strings.TrimSpace(m.inputInteraction[interactionSummary].Value()),
+		FollowUp:     strings.TrimSpace(m.inputInteraction[interactionFollowUp].Value()),
+	}
+	for _, name := range strings.Split(m.inputInteraction[interactionWith].Value(), ",") {
+		if name = strings.TrimSpace(name); name == "" {
+			continue
+		}
+		id, err := findByName(m.db.People, name)
+		if err != nil {
+			return in, err
+		}
+		if !in.Involves(id) {
+			in.Participants = append(in.Participants, id)
+		}
+	}
+	return in, in.Check()
+}
+
+// findByName returns the id of the one person called name, ignoring case
+func findByName(people []person.Person, name string) (string, error) {
+	ids := []string{}
+	for _, p := range people {
+		if strings.EqualFold(p.Name, name) {
+			ids = append(ids, p.ID)
+		}
+	}
+	switch len(ids) {
+	case 0:
+		return "", fmt.Errorf("there is nobody called %q", name)
+	case 1:
+		return ids[0], nil
+	}
+	return "", fmt.Errorf("%d people are called %q", len(ids), name)
+}
+
+func (m model) interactionFormView() string {
+	labels := []string{"Date:", "Kind:", "With:", "Summary:", "Follow up on:"}
+	s := titleStyle.Render("Log Interaction with "+m.selectedPerson.Name) + "\n\n"
+	for i, label := range labels {
+		s += label + "\n" + m.inputInteraction[i].View() + "\n\n"
+	}
+	return s + infoStyle.Render("Enter on Follow up to Save | Tab/Shift+Tab: Next/Previous Field | ESC: Back")
+}
+
+// interactionsView renders the newest interactions of the selected person
+func (m model) interactionsView() string {
+	list := []interaction.Interaction{}
+	for _, in := range m.db.Interactions {
+		if in.Involves(m.selectedPerson.ID) {
+			list = append(list, in)
+		}
+	}
+	if len(list) == 0 {
+		return infoStyle.Render("(Nothing logged yet - Press i to log an interaction)")
+	}
+	interaction.Sort(list)
+	lines := []string{}
+	for i := len(list) - 1; i >= 0 && len(lines) < detailInteractions; i-- {
+		in := list[i]
+		line := infoStyle.Render(in.Date) + "  " + in.Kind
+		if others := m.otherParticipants(in); others != "" {
+			line += " with " + others
+		}
+		if in.Summary != "" {
+			line += ": " + in.Summary
+		}
+		if in.FollowUp != "" {
+			line += infoStyle.Render(" (follow up on " + in.FollowUp + ")")
+		}
+		lines = append(lines, line)
+	}
+	if hidden := len(list) - len(lines); hidden > 0 {
+		lines = append(lines, infoStyle.Render(fmt.Sprintf("... and %d older", hidden)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// otherParticipants names everyone in in besides the selected person
+func (m model) otherParticipants(in interaction.Interaction) string {
+	names := []string{}
+	for _, id := range in.Participants {
+		if id != m.selectedPerson.ID {
+			names = append(names, getName(m.db.People, id))
+		}
+	}
+	return strings.Join(names, ", ")
+}
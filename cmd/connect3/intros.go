@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/graph"
+	"github.com/N3moAhead/connect3/internal/person"
+)
+
+// detailCommons is how many people in common and introductions the detail view shows
+const detailCommons = 3
+
+// personIDs returns the ids of people, in their order
+func personIDs(people []person.Person) []string {
+	ids := make([]string, len(people))
+	for i, p := range people {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+// namesOf joins the names of ids, e.g. "Ada, Grace"
+func namesOf(people []person.Person, ids []string) string {
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = getName(people, id)
+	}
+	return strings.Join(names, ", ")
+}
+
+// refreshNetwork indexes the relations of the snapshot and works out the introductions
+func (m *model) refreshNetwork() {
+	m.network = graph.New(m.db.Relations)
+	m.intros = m.network.Intros(personIDs(m.db.People), config.INTRO_MIN_STRENGTH)
+}
+
+// commonsView renders who shares the most connections with the selected person
+func (m model) commonsView() string {
+	commons := m.network.Commons(m.selectedPerson.ID)
+	if len(commons) == 0 {
+		return infoStyle.Render("(Nobody yet - People show up here once you share connections)")
+	}
+	lines := []string{}
+	for _, c := range commons[:min(len(commons), detailCommons)] {
+		lines = append(lines, fmt.Sprintf("%s: %d shared (%s)", getName(m.db.People, c.ID), len(c.Mutual), namesOf(m.db.People, c.Mutual)))
+	}
+	if hidden := len(commons) - len(lines); hidden > 0 {
+		lines = append(lines, infoStyle.Render(fmt.Sprintf("... and %d more", hidden)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// introsView renders the introductions the selected person could make or get
+func (m model) introsView() string {
+	id := m.selectedPerson.ID
+	lines := []string{}
+	hidden := 0
+	for _, in := range m.intros {
+		if !in.Involves(id) {
+			continue
+		}
+		if len(lines) == detailCommons {
+			hidden++
+			continue
+		}
+		lines = append(lines, describeIntro(m.db.People, in, id))
+	}
+	if len(lines) == 0 {
+		return infoStyle.Render("(Nothing to suggest)")
+	}
+	if hidden > 0 {
+		lines = append(lines, infoStyle.Render(fmt.Sprintf("... and %d more, see c3 suggest-intros", hidden)))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeIntro renders an introduction from the point of view of id,
+// or of nobody in particular if id is empty
+func describeIntro(people []person.Person, in graph.Intro, id string) string {
+	score := fmt.Sprintf(" (%d)", in.Strength)
+	switch id {
+	case in.A, in.B:
+		other := in.B
+		if id == in.B {
+			other = in.A
+		}
+		return "Meet " + getName(people, other) + " through " + namesOf(people, in.Via) + score
+	case "":
+		return getName(people, in.A) + " and " + getName(people, in.B) + ", both know " + namesOf(people, in.Via) + score
+	}
+	return "Introduce " + getName(people, in.A) + " and " + getName(people, in.B) + score
+}
@@ -1,21 +1,27 @@
 package main
 
 import (
-	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"os"
 	"path/filepath"
+	"reflect"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/N3moAhead/connect3/internal/backup"
 	"github.com/N3moAhead/connect3/internal/config"
 	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/graph"
+	"github.com/N3moAhead/connect3/internal/lock"
 	"github.com/N3moAhead/connect3/internal/migration"
 	"github.com/N3moAhead/connect3/internal/person"
 	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/store"
+	"github.com/N3moAhead/connect3/internal/undo"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 	"github.com/charmbracelet/bubbles/textarea"
@@ -33,6 +39,8 @@ var (
 	infoStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
 	warnStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("196")).Bold(true) // Red
 	tagStyle   = lipgloss.NewStyle().Foreground(lipgloss.Color("39")).MarginRight(1)
+
+	bannerStyle = lipgloss.NewStyle().Border(lipgloss.RoundedBorder()).BorderForeground(lipgloss.Color("196")).Padding(0, 1)
 )
 
 // --- APPLICATION STATES ---
@@ -48,20 +56,39 @@ const (
 	viewConfirmDeletePerson
 	viewConfirmDeleteRelation
 	viewTagSelect
+	viewFields       // Custom field definitions
+	viewFieldForm    // Used for Create and Edit of a custom field
+	viewPeopleFilter // Filter the people list by custom fields
+	viewInteractionForm
+	viewOverdue // Keep in touch dashboard
+	viewReminderForm
+	viewAgenda // What is due today and this week
+	viewGraph  // Ego network of a person
+	viewPathTarget
+	viewPath  // Ways from the selected person to someone else
+	viewStats // Hubs, brokers and communities of the whole network
 )
 
 // --- MAIN MODEL ---
 
 type model struct {
-	state  sessionState
-	db     db.Database
-	dbPath string
+	state sessionState
+	store store.Store
+	db    db.Database // Read only snapshot of the store, refreshed after every write
+	err   error       // Last error returned by the store
+
+	readOnly bool   // Another instance holds the lock, every write is refused
+	notice   string // Info for the user, cleared on the next key press
+	conflict string // Warning about outside changes to what is open in a form
 
 	// Lists
 	listPeople    list.Model
 	listRelations list.Model // Embedded in Detail View
+	listOverdue   list.Model // People due for contact
+	listAgenda    list.Model // Reminders, birthdays and follow-ups that are due
 
 	// Selections
+	backTo         sessionState // Where ESC in the detail view goes
 	selectedPerson *person.Person
 	selectedRel    *relation.Relation
 	targetPerson   *person.Person // For creating new relations
@@ -70,13 +97,56 @@ type model struct {
 	isEditing    bool // Are we creating or editing?
 	inputName    textinput.Model
 	inputNotes   textarea.Model
+	inputDetails []textinput.Model // Company to links, see detailFields
+	inputCustom  []textinput.Model // One per custom field, in the order of customFields
+	customFields []db.FieldDef     // Fields when the form was opened, a reload may change db.Fields
+	formFocus    int               // Field of the person form with the cursor
 	inputRelDesc textinput.Model
 	inputRelStr  textinput.Model
 
+	inputInteraction []textinput.Model // Date to follow-up, see interactionDate
+	interactionFocus int
+
+	inputReminder []textinput.Model // Date, text and repeat, see reminderDate
+	reminderFocus int
+
+	// History pane in the detail view
+	showHistory bool
+	events      []db.Event // History of the selected person, oldest first
+
 	// Tag Selection
 	listTags list.Model      // list of available tags
 	inputTag textinput.Model // Dedicated input for tags
 	tempTags []string        // list of tags which we are editing
+
+	// Custom fields
+	listFields   list.Model
+	inputField   []textinput.Model // Name, type and options, see fieldFormName
+	fieldFocus   int
+	editingField *db.FieldDef // nil while creating a new field
+
+	// Graph view
+	graphCenter   string
+	graphBackTo   sessionState // Where the detail view of the center went back to
+	graphHops     int          // 1 or 2
+	graphNodes    []graphNode  // Center first, see egoNetwork
+	graphHidden   int          // People who did not fit on the rings
+	graphSelected int
+	graphWidth    int
+	graphHeight   int
+
+	path  pathResult  // Shown in viewPath
+	stats graph.Stats // Shown in viewStats
+
+	// The relations of db indexed, rebuilt with every snapshot because
+	// the detail view renders on every tick
+	network *graph.Index
+	intros  []graph.Intro // Introductions in the whole network, see refreshNetwork
+
+	// Filter of the people list by custom fields
+	inputFilter  textinput.Model
+	filterText   string
+	peopleFilter []fieldCondition
 }
 
 func getDefaultDBPath() string {
@@ -94,8 +164,8 @@ func getDefaultDBPath() string {
 	return filepath.Join(home, ".local", "share", "connect3", config.DB_FILE_NAME)
 }
 
-func initialModel(dbPath string) model {
-	database := loadData(dbPath)
+func initialModel(st store.Store, readOnly bool) model {
+	database, err := st.Snapshot()
 
 	// 1. Init People List
 	items := make([]list.Item, len(database.People))
@@ -107,6 +177,13 @@ func initialModel(dbPath string) model {
 	l.AdditionalShortHelpKeys = func() []key.Binding {
 		return []key.Binding{
 			key.NewBinding(key.WithKeys("n"), key.WithHelp("n", "New Person")),
+			key.NewBinding(key.WithKeys("f"), key.WithHelp("f", "Filter by Field")),
+			key.NewBinding(key.WithKeys("F"), key.WithHelp("F", "Custom Fields")),
+			key.NewBinding(key.WithKeys("o"), key.WithHelp("o", "Overdue")),
+			key.NewBinding(key.WithKeys("a"), key.WithHelp("a", "Agenda")),
+			key.NewBinding(key.WithKeys("S"), key.WithHelp("S", "Stats")),
+			key.NewBinding(key.WithKeys("u"), key.WithHelp("u", "Undo")),
+			key.NewBinding(key.WithKeys("ctrl+r"), key.WithHelp("ctrl+r", "Redo")),
 		}
 	}
 
@@ -147,24 +224,77 @@ func initialModel(dbPath string) model {
 	tiTag.Placeholder = "Type to search or create new tag..."
 	tiTag.CharLimit = 30
 
-	return model{
+	// 5. Init Custom Fields
+	lf := newFieldList()
+	items = make([]list.Item, len(database.Fields))
+	for i, f := range database.Fields {
+		items[i] = fieldItem{def: f}
+	}
+	lf.SetItems(items)
+
+	tiFilter := textinput.New()
+	tiFilter.Placeholder = "timezone=CET; met at~berlin"
+	tiFilter.Width = 50
+
+	m := model{
 		state:         viewListPeople,
+		store:         st,
 		db:            database,
-		dbPath:        dbPath,
+		err:           err,
+		readOnly:      readOnly,
 		listPeople:    l,
 		listRelations: lr,
 		inputName:     ti,
 		inputNotes:    ta,
+		inputDetails:  newDetailInputs(),
 		inputRelDesc:  tiRelDesc,
 		inputRelStr:   tiRelStr,
 		listTags:      lt,
 		inputTag:      tiTag,
 		tempTags:      []string{},
+		listFields:    lf,
+		inputField:    newFieldInputs(),
+		inputFilter:   tiFilter,
+
+		inputInteraction: newInteractionInputs(),
+		listOverdue:      newOverdueList(),
+		inputReminder:    newReminderInputs(),
+		listAgenda:       newAgendaList(),
+		graphHops:        2,
 	}
+	m.refreshNetwork()
+	m.refreshOverdueList()
+	m.refreshAgendaList()
+	return m
 }
 
 func (m model) Init() tea.Cmd {
-	return nil
+	return watchDB(m.store)
+}
+
+// --- FILE WATCHING ---
+
+// watchInterval is how often the database is checked for outside changes
+const watchInterval = time.Second
+
+type watchTickMsg struct{}
+
+// dbChangedMsg is sent when someone else changed the database file
+type dbChangedMsg struct{}
+
+// watchDB polls the store and reports outside changes as a dbChangedMsg.
+// Stores that can not reload are not watched.
+func watchDB(st store.Store) tea.Cmd {
+	r, ok := st.(store.Reloader)
+	if !ok {
+		return nil
+	}
+	return tea.Tick(watchInterval, func(time.Time) tea.Msg {
+		if changed, err := r.Changed(); err == nil && changed {
+			return dbChangedMsg{}
+		}
+		return watchTickMsg{}
+	})
 }
 
 // --- UPDATE ---
@@ -173,11 +303,23 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	var cmd tea.Cmd
 
 	switch msg := msg.(type) {
+	case watchTickMsg:
+		return m, watchDB(m.store)
+	case dbChangedMsg:
+		m.reload()
+		return m, watchDB(m.store)
+	case tea.KeyMsg:
+		m.notice = ""
 	case tea.WindowSizeMsg:
 		h, v := docStyle.GetFrameSize()
-		m.listPeople.SetSize(msg.Width-h, msg.Height-v)
+		listH := msg.Height - v
+		if m.readOnly {
+			listH -= 2 // Read-only banner
+		}
+		m.listPeople.SetSize(msg.Width-h, listH)
 
-		relHeight := max(msg.Height-v-12, 5)
+		// Leaves room for the interactions, people in common and introductions below the connections
+		relHeight := max(msg.Height-v-18-detailInteractions-2*detailCommons, 5)
 		m.listRelations.SetSize(msg.Width-h, relHeight)
 
 		tagListH := msg.Height - v - 6
@@ -185,6 +327,13 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			tagListH = 1
 		}
 		m.listTags.SetSize(msg.Width-h, tagListH)
+		m.listFields.SetSize(msg.Width-h, msg.Height-v)
+		m.listOverdue.SetSize(msg.Width-h, msg.Height-v)
+		m.listAgenda.SetSize(msg.Width-h, msg.Height-v)
+
+		// Title, legend, overflow and help around the graph
+		m.graphWidth, m.graphHeight = msg.Width-h, max(msg.Height-v-4, 5)
+		layoutGraph(m.graphNodes, m.graphWidth, m.graphHeight)
 	}
 
 	switch m.state {
@@ -200,18 +349,60 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.tempTags = []string{}
 				m.state = viewPersonForm
 				m.isEditing = false
-				m.inputName.SetValue("")
-				m.inputNotes.SetValue("")
-				m.inputName.Focus()
-				m.inputNotes.Blur()
+				m.fillPersonForm(person.Person{})
+				m.focusForm(fieldName)
+				return m, nil
+			case "f", "F":
+				if m.listPeople.FilterState() == list.Filtering {
+					break
+				}
+				if msg.String() == "F" {
+					m.state = viewFields
+					return m, nil
+				}
+				m.inputFilter.SetValue(m.filterText)
+				m.inputFilter.CursorEnd()
+				m.inputFilter.Focus()
+				m.state = viewPeopleFilter
+				return m, nil
+			case "o":
+				if m.listPeople.FilterState() == list.Filtering {
+					break
+				}
+				m.listOverdue.ResetSelected()
+				m.state = viewOverdue
+				return m, nil
+			case "a":
+				if m.listPeople.FilterState() == list.Filtering {
+					break
+				}
+				m.refreshAgendaList()
+				m.listAgenda.ResetSelected()
+				m.state = viewAgenda
+				return m, nil
+			case "S":
+				if m.listPeople.FilterState() == list.Filtering {
+					break
+				}
+				m.openStats()
 				return m, nil
 			case "enter":
 				if i, ok := m.listPeople.SelectedItem().(person.Person); ok {
 					m.selectedPerson = &i
+					m.backTo = viewListPeople
 					m.state = viewDetail
 					m.refreshRelationList()
+					m.refreshHistory()
 				}
 				return m, nil
+			case "u":
+				if m.listPeople.FilterState() != list.Filtering {
+					m.undoRedo(false)
+					return m, nil
+				}
+			case "ctrl+r":
+				m.undoRedo(true)
+				return m, nil
 			}
 		}
 		m.listPeople, cmd = m.listPeople.Update(msg)
@@ -225,25 +416,22 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyMsg:
 			switch msg.String() {
 			case "esc", "backspace":
-				m.state = viewListPeople
+				m.state = m.backTo
 				m.selectedPerson = nil
 				return m, nil
 			case "E": // Edit Person
 				m.tempTags = m.selectedPerson.Tags
 				m.state = viewPersonForm
 				m.isEditing = true
-				m.inputName.SetValue(m.selectedPerson.Name)
-				m.inputNotes.SetValue(m.selectedPerson.Notes)
-				m.inputName.Focus()
-				m.inputNotes.Blur()
+				m.fillPersonForm(*m.selectedPerson)
+				m.focusForm(fieldName)
 				return m, nil
 
 			// --- Ctrl+g für Tags ---
 			case "ctrl+g":
 				m.tempTags = m.selectedPerson.Tags
 				m.isEditing = true
-				m.inputName.SetValue(m.selectedPerson.Name)
-				m.inputNotes.SetValue(m.selectedPerson.Notes)
+				m.fillPersonForm(*m.selectedPerson)
 
 				// Reset Tag View
 				m.inputTag.SetValue("")
@@ -256,6 +444,31 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.state = viewConfirmDeletePerson
 				return m, nil
 
+			case "u":
+				m.undoRedo(false)
+				return m, nil
+			case "ctrl+r":
+				m.undoRedo(true)
+				return m, nil
+
+			case "i":
+				m.openInteractionForm()
+				return m, nil
+			case "r":
+				m.openReminderForm()
+				return m, nil
+			case "g":
+				m.openGraph()
+				return m, nil
+			case "p":
+				m.openPathTarget()
+				return m, nil
+
+			case "H":
+				m.showHistory = !m.showHistory
+				m.refreshHistory()
+				return m, nil
+
 			// Relation Logic
 			case "n":
 				m.state = viewRelationTarget
@@ -296,6 +509,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyMsg:
 			switch msg.String() {
 			case "esc":
+				m.conflict = ""
 				if m.isEditing {
 					m.state = viewDetail
 				} else {
@@ -303,13 +517,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 				return m, nil
 			case "tab":
-				if m.inputName.Focused() {
-					m.inputName.Blur()
-					m.inputNotes.Focus()
-				} else {
-					m.inputNotes.Blur()
-					m.inputName.Focus()
-				}
+				m.focusForm((m.formFocus + 1) % (m.fieldNotes() + 1))
+				return m, nil
+			case "shift+tab":
+				m.focusForm((m.formFocus + m.fieldNotes()) % (m.fieldNotes() + 1))
 				return m, nil
 
 			// --- Ctrl+g für Tags ---
@@ -319,38 +530,36 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				m.updateTagListFilter()
 				m.state = viewTagSelect
 
-				m.inputName.Blur()
-				m.inputNotes.Blur()
+				m.blurForm()
 				return m, nil
 
 			case "enter":
-				if m.inputName.Focused() {
-					m.inputName.Blur()
-					m.inputNotes.Focus()
+				if m.formFocus != m.fieldNotes() {
+					m.focusForm(m.formFocus + 1)
 					return m, nil
 				}
 				// Save Logic
 				if m.isEditing {
-					for i, p := range m.db.People {
-						if p.ID == m.selectedPerson.ID {
-							m.db.People[i].Name = m.inputName.Value()
-							m.db.People[i].Notes = m.inputNotes.Value()
-							m.db.People[i].Tags = m.tempTags
-							m.selectedPerson = &m.db.People[i]
-							break
-						}
+					p := *m.selectedPerson
+					if err := m.readPersonForm(&p); err != nil {
+						m.err = err
+						return m, nil
+					}
+					if !m.do("Edit "+p.Name, func(tx store.Tx) error { return tx.UpdatePerson(p) }) {
+						// Stay in the form so the edit is not lost
+						return m, nil
 					}
+					m.selectedPerson = &p
 				} else {
-					newP := person.Person{
-						ID:    uuid.New().String(),
-						Name:  m.inputName.Value(),
-						Notes: m.inputNotes.Value(),
-						Tags:  m.tempTags,
+					newP := person.Person{ID: uuid.New().String()}
+					if err := m.readPersonForm(&newP); err != nil {
+						m.err = err
+						return m, nil
+					}
+					if !m.do("Create "+newP.Name, func(tx store.Tx) error { return tx.CreatePerson(newP) }) {
+						return m, nil
 					}
-					m.db.People = append(m.db.People, newP)
 				}
-				saveData(m.db, m.dbPath)
-				m.listPeople.SetItems(peopleToItems(m.db.People))
 
 				if m.isEditing {
 					m.state = viewDetail
@@ -360,10 +569,21 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				return m, nil
 			}
 		}
-		var cmdName, cmdNotes tea.Cmd
-		m.inputName, cmdName = m.inputName.Update(msg)
-		m.inputNotes, cmdNotes = m.inputNotes.Update(msg)
-		return m, tea.Batch(cmdName, cmdNotes)
+		cmds := make([]tea.Cmd, 0, len(m.inputDetails)+len(m.inputCustom)+2)
+		var c tea.Cmd
+		m.inputName, c = m.inputName.Update(msg)
+		cmds = append(cmds, c)
+		m.inputNotes, c = m.inputNotes.Update(msg)
+		cmds = append(cmds, c)
+		for i := range m.inputDetails {
+			m.inputDetails[i], c = m.inputDetails[i].Update(msg)
+			cmds = append(cmds, c)
+		}
+		for i := range m.inputCustom {
+			m.inputCustom[i], c = m.inputCustom[i].Update(msg)
+			cmds = append(cmds, c)
+		}
+		return m, tea.Batch(cmds...)
 
 	// ---------------------------------------------------------
 	// 4. TAG SELECTION VIEW
@@ -374,7 +594,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			switch msg.String() {
 			case "esc":
 				m.state = viewPersonForm
-				m.inputName.Focus()
+				m.focusForm(fieldName)
 				return m, nil
 
 			case "down", "up":
@@ -408,7 +628,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				}
 
 				m.state = viewPersonForm
-				m.inputName.Focus()
+				m.focusForm(fieldName)
 				return m, nil
 			}
 
@@ -425,22 +645,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
 			if msg.String() == "y" || msg.String() == "Y" {
-				newRels := []relation.Relation{}
-				for _, r := range m.db.Relations {
-					if r.FromID != m.selectedPerson.ID && r.ToID != m.selectedPerson.ID {
-						newRels = append(newRels, r)
-					}
-				}
-				m.db.Relations = newRels
-				newPeople := []person.Person{}
-				for _, p := range m.db.People {
-					if p.ID != m.selectedPerson.ID {
-						newPeople = append(newPeople, p)
-					}
+				id := m.selectedPerson.ID
+				if !m.do("Delete "+m.selectedPerson.Name, func(tx store.Tx) error { return tx.DeletePerson(id) }) {
+					m.state = viewDetail
+					return m, nil
 				}
-				m.db.People = newPeople
-				saveData(m.db, m.dbPath)
-				m.listPeople.SetItems(peopleToItems(m.db.People))
 				m.state = viewListPeople
 				m.selectedPerson = nil
 			} else if msg.String() == "n" || msg.String() == "N" || msg.String() == "esc" {
@@ -483,6 +692,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		case tea.KeyMsg:
 			switch msg.String() {
 			case "esc":
+				m.conflict = ""
 				m.state = viewDetail
 				m.listPeople.Title = "People"
 				return m, nil
@@ -508,12 +718,11 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					strVal = 5
 				}
 				if m.isEditing {
-					for i, r := range m.db.Relations {
-						if r.ID == m.selectedRel.ID {
-							m.db.Relations[i].Strength = strVal
-							m.db.Relations[i].Description = m.inputRelDesc.Value()
-							break
-						}
+					r := *m.selectedRel
+					r.Strength = strVal
+					r.Description = m.inputRelDesc.Value()
+					if !m.do("Edit connection "+m.relationName(r), func(tx store.Tx) error { return tx.UpdateRelation(r) }) {
+						return m, nil
 					}
 				} else {
 					newRel := relation.Relation{
@@ -523,9 +732,10 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 						Strength:    strVal,
 						Description: m.inputRelDesc.Value(),
 					}
-					m.db.Relations = append(m.db.Relations, newRel)
+					if !m.do("Connect "+m.relationName(newRel), func(tx store.Tx) error { return tx.CreateRelation(newRel) }) {
+						return m, nil
+					}
 				}
-				saveData(m.db, m.dbPath)
 				m.refreshRelationList()
 				m.state = viewDetail
 				m.listPeople.Title = "People"
@@ -541,20 +751,60 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		switch msg := msg.(type) {
 		case tea.KeyMsg:
 			if msg.String() == "y" || msg.String() == "Y" {
-				newRels := []relation.Relation{}
-				for _, r := range m.db.Relations {
-					if r.ID != m.selectedRel.ID {
-						newRels = append(newRels, r)
-					}
-				}
-				m.db.Relations = newRels
-				saveData(m.db, m.dbPath)
+				id := m.selectedRel.ID
+				m.do("Delete connection "+m.relationName(*m.selectedRel), func(tx store.Tx) error { return tx.DeleteRelation(id) })
 				m.refreshRelationList()
 				m.state = viewDetail
 			} else if msg.String() == "n" || msg.String() == "N" || msg.String() == "esc" {
 				m.state = viewDetail
 			}
 		}
+
+	// ---------------------------------------------------------
+	// 7. CUSTOM FIELDS
+	// ---------------------------------------------------------
+	case viewFields:
+		return m.updateFields(msg)
+	case viewFieldForm:
+		return m.updateFieldForm(msg)
+	case viewPeopleFilter:
+		return m.updatePeopleFilter(msg)
+
+	// ---------------------------------------------------------
+	// 8. INTERACTIONS
+	// ---------------------------------------------------------
+	case viewInteractionForm:
+		return m.updateInteractionForm(msg)
+
+	// ---------------------------------------------------------
+	// 9. KEEP IN TOUCH
+	// ---------------------------------------------------------
+	case viewOverdue:
+		return m.updateOverdue(msg)
+
+	// ---------------------------------------------------------
+	// 10. REMINDERS & AGENDA
+	// ---------------------------------------------------------
+	case viewReminderForm:
+		return m.updateReminderForm(msg)
+	case viewAgenda:
+		return m.updateAgenda(msg)
+
+	// ---------------------------------------------------------
+	// 11. GRAPH
+	// ---------------------------------------------------------
+	case viewGraph:
+		return m.updateGraph(msg)
+	case viewPathTarget:
+		return m.updatePathTarget(msg)
+	case viewPath:
+		return m.updatePath(msg)
+
+	// ---------------------------------------------------------
+	// 12. STATS
+	// ---------------------------------------------------------
+	case viewStats:
+		return m.updateStats(msg)
 	}
 
 	return m, nil
@@ -563,12 +813,20 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 // --- VIEW ---
 
 func (m model) View() string {
+	return docStyle.Render(m.errorBanner() + m.viewContent())
+}
+
+func (m model) viewContent() string {
 	switch m.state {
 	case viewListPeople:
-		return docStyle.Render(m.listPeople.View())
+		if m.readOnly {
+			return warnStyle.Render("READ-ONLY") + infoStyle.Render(" The database is open in another c3 instance, changes can not be saved.") +
+				"\n\n" + m.listPeople.View()
+		}
+		return m.listPeople.View()
 
 	case viewRelationTarget:
-		return docStyle.Render(m.listPeople.View())
+		return m.listPeople.View()
 
 	case viewDetail:
 		if m.selectedPerson == nil {
@@ -582,13 +840,28 @@ func (m model) View() string {
 			tagBlock += "\n\n"
 		}
 		s := titleStyle.Render(m.selectedPerson.Name) + "\n"
+		s += contactView(*m.selectedPerson)
+		s += customView(m.db.Fields, *m.selectedPerson)
+		s += m.cadenceView(*m.selectedPerson)
+		s += m.remindersView()
 		s += infoStyle.Render(m.selectedPerson.Notes) + "\n\n"
 		s += tagBlock
-		help := infoStyle.Render("E: Edit Person | D: Delete Person | Ctrl+g: Tags | n: New Rel | e: Edit Rel | d: Del Rel | ESC: Back")
+		help := infoStyle.Render("E: Edit Person | D: Delete Person | Ctrl+g: Tags | n: New Rel | e: Edit Rel | d: Del Rel | i: Log Interaction | r: Reminder | g: Graph | p: Path to... | u/Ctrl+r: Undo/Redo | H: History | ESC: Back")
 		s += help + "\n\n"
+		if m.showHistory {
+			s += lipgloss.NewStyle().Underline(true).Render("History:") + "\n"
+			s += m.historyView()
+			return s
+		}
 		s += lipgloss.NewStyle().Underline(true).Render("Connections:") + "\n"
-		s += m.listRelations.View()
-		return docStyle.Render(s)
+		s += m.listRelations.View() + "\n\n"
+		s += lipgloss.NewStyle().Underline(true).Render("Interactions:") + "\n"
+		s += m.interactionsView() + "\n\n"
+		s += lipgloss.NewStyle().Underline(true).Render("In Common:") + "\n"
+		s += m.commonsView() + "\n\n"
+		s += lipgloss.NewStyle().Underline(true).Render("Introductions:") + "\n"
+		s += m.introsView()
+		return s
 
 	case viewPersonForm:
 		title := "Create New Person"
@@ -602,14 +875,16 @@ func (m model) View() string {
 		if tagsStr == "" {
 			tagsStr = infoStyle.Render("(No tags - Press Ctrl+g to add)")
 		}
-		return docStyle.Render(fmt.Sprintf(
-			"%s\n\nName:\n%s\n\nNotes:\n%s\n\nTags:\n%s\n\n%s",
+		return fmt.Sprintf(
+			"%s\n\nName:\n%s\n\n%s%sNotes:\n%s\n\nTags:\n%s\n\n%s",
 			titleStyle.Render(title),
 			m.inputName.View(),
+			m.personFormDetails(),
+			m.customFormView(),
 			m.inputNotes.View(),
 			tagsStr,
-			infoStyle.Render("Enter on Notes to Save | Ctrl+g: Manage Tags"),
-		))
+			infoStyle.Render("Enter on Notes to Save | Tab/Shift+Tab: Next/Previous Field | Ctrl+g: Manage Tags"),
+		)
 
 	case viewTagSelect:
 		listView := m.listTags.View()
@@ -619,13 +894,13 @@ func (m model) View() string {
 			listView = infoStyle.Render("(No existing tags found - Type to create new)")
 		}
 
-		return docStyle.Render(fmt.Sprintf(
+		return fmt.Sprintf(
 			"%s\n\n%s\n\n%s\n%s",
 			titleStyle.Render("Manage Tags"),
 			m.inputTag.View(),
 			infoStyle.Render("Existing Tags:"),
 			listView,
-		))
+		)
 
 	case viewRelationForm:
 		// ... (wie gehabt)
@@ -639,18 +914,41 @@ func (m model) View() string {
 		} else {
 			targetName = m.targetPerson.Name
 		}
-		return docStyle.Render(fmt.Sprintf(
+		return fmt.Sprintf(
 			"Connection with %s\n\nStrength (1-5):\n%s\n\nDescription:\n%s\n\n%s",
 			titleStyle.Render(targetName),
 			m.inputRelStr.View(),
 			m.inputRelDesc.View(),
 			infoStyle.Render("Enter on Description to Save"),
-		))
+		)
+
+	case viewFields:
+		return m.listFields.View()
+	case viewFieldForm:
+		return m.fieldFormView()
+	case viewPeopleFilter:
+		return m.peopleFilterView()
+	case viewInteractionForm:
+		return m.interactionFormView()
+	case viewOverdue:
+		return m.listOverdue.View()
+	case viewReminderForm:
+		return m.reminderFormView()
+	case viewAgenda:
+		return m.listAgenda.View()
+	case viewGraph:
+		return m.graphView()
+	case viewPathTarget:
+		return m.listPeople.View()
+	case viewPath:
+		return m.pathView()
+	case viewStats:
+		return m.statsView()
 
 	case viewConfirmDeletePerson:
-		return docStyle.Render(fmt.Sprintf("\n%s\n\n%s\n\n(y/n)", warnStyle.Render("WARNING"), "Do you really want to delete this person?"))
+		return fmt.Sprintf("\n%s\n\n%s\n\n(y/n)", warnStyle.Render("WARNING"), "Do you really want to delete this person?")
 	case viewConfirmDeleteRelation:
-		return docStyle.Render(fmt.Sprintf("\n%s\n\n%s\n\n(y/n)", warnStyle.Render("DELETE CONNECTION"), "Do you really want to delete this connection?"))
+		return fmt.Sprintf("\n%s\n\n%s\n\n(y/n)", warnStyle.Render("DELETE CONNECTION"), "Do you really want to delete this connection?")
 	}
 	return ""
 }
@@ -751,37 +1049,99 @@ func peopleToItems(people []person.Person) []list.Item {
 	return items
 }
 
-func loadData(dbPath string) db.Database {
-	f, err := os.Open(dbPath)
-	if err != nil {
-		return db.Database{People: []person.Person{}, Relations: []relation.Relation{}, Version: config.DB_FORMAT_VERSION}
+// do runs a write as one undoable operation and saves its result
+func (m *model) do(name string, fn func(tx store.Tx) error) bool {
+	return m.save(undo.Do(m.store, name, fn))
+}
+
+// undoRedo reverts the last operation, or applies the last reverted one again
+func (m *model) undoRedo(redo bool) {
+	var op db.Operation
+	var err error
+	if redo {
+		op, err = undo.Redo(m.store)
+	} else {
+		op, err = undo.Undo(m.store)
+	}
+	if errors.Is(err, undo.ErrNothingToUndo) || errors.Is(err, undo.ErrNothingToRedo) {
+		m.notice = strings.ToUpper(err.Error()[:1]) + err.Error()[1:]
+		return
+	}
+	if errors.Is(err, undo.ErrStale) {
+		// Nothing was changed but the operation is gone from the history
+		m.save(nil)
+		m.notice = strings.ToUpper(err.Error()[:1]) + err.Error()[1:]
+		return
+	}
+	if !m.save(err) {
+		return
+	}
+	if redo {
+		m.notice = "Redone: " + op.Name
+	} else {
+		m.notice = "Undone: " + op.Name
 	}
-	defer f.Close()
-	byteValue, _ := io.ReadAll(f)
-	var database db.Database
-	json.Unmarshal(byteValue, &database)
+	m.syncSelection()
+}
 
-	dirty := false
-	for i := range database.Relations {
-		if database.Relations[i].ID == "" {
-			database.Relations[i].ID = uuid.New().String()
-			dirty = true
-		}
+// relationName describes a relation as "Alice - Bob"
+func (m model) relationName(r relation.Relation) string {
+	return getName(m.db.People, r.FromID) + " - " + getName(m.db.People, r.ToID)
+}
+
+// errorBanner renders the last store error on top of every view.
+// It stays until the next write goes through.
+func (m model) errorBanner() string {
+	s := ""
+	if m.err != nil {
+		s += bannerStyle.Render(warnStyle.Render("Error: ")+m.err.Error()) + "\n\n"
+	}
+	if m.conflict != "" {
+		s += bannerStyle.Render(warnStyle.Render("Conflict: ")+m.conflict) + "\n\n"
 	}
-	if dirty {
-		saveData(database, dbPath)
+	if m.notice != "" {
+		s += infoStyle.Render(m.notice) + "\n\n"
 	}
-	return database
+	return s
 }
 
-func saveData(database db.Database, dbPath string) {
-	file, _ := json.MarshalIndent(database, "", " ")
-	_ = os.WriteFile(dbPath, file, 0644)
+// save takes the result of a store write, reloads the snapshot
+// and reports if the write went through
+func (m *model) save(err error) bool {
+	m.err = nil
+	if err != nil {
+		m.err = fmt.Errorf("your change was not saved: %w", err)
+	} else {
+		m.conflict = ""
+	}
+	if database, snapErr := m.store.Snapshot(); snapErr == nil {
+		m.db = database
+	} else if m.err == nil {
+		m.err = snapErr
+	}
+	m.refreshPeopleList()
+	m.refreshFieldList()
+	m.refreshOverdueList()
+	m.refreshAgendaList()
+	m.refreshNetwork()
+	m.refreshGraph()
+	m.refreshHistory()
+	return err == nil
 }
 
 func main() {
-	dbFlag := flag.String("db", "", "Path to the database json file")
+	dbFlag := flag.String("db", "", "Path to the database file (.json, or .db/.sqlite for sqlite)")
+	policy := backup.Policy{}
+	flag.IntVar(&policy.Last, "backup-last", config.BACKUP_KEEP_LAST, "Number of most recent backups to keep")
+	flag.IntVar(&policy.Daily, "backup-daily", config.BACKUP_KEEP_DAILY, "Number of days to keep one backup for")
+	flag.IntVar(&policy.Weekly, "backup-weekly", config.BACKUP_KEEP_WEEKLY, "Number of weeks to keep one backup for")
+	flag.Usage = usage
 	flag.Parse()
+	// A broken migration chain is a bug in c3, better to notice before touching any data
+	if err := migration.Check(); err != nil {
+		fmt.Printf("Error in the migrations: %v\n", err)
+		os.Exit(1)
+	}
 	dbPath := *dbFlag
 	if dbPath == "" {
 		dbPath = getDefaultDBPath()
@@ -791,14 +1151,296 @@ func main() {
 		fmt.Printf("Error creating directory %s: %v\n", dir, err)
 		os.Exit(1)
 	}
-	if err := migration.RunMigrations(dbPath); err != nil {
-		fmt.Printf("Error running migrations: %v\n", err)
+
+	// Only one instance may write to the database at a time,
+	// everyone else gets to look but not touch
+	readOnly := false
+	dbLock, err := lock.Acquire(dbPath)
+	if errors.Is(err, lock.ErrLocked) {
+		readOnly = true
+		fmt.Printf("Opening read-only: %v\n", err)
+	} else if err != nil {
+		fmt.Printf("Error locking database: %v\n", err)
+		os.Exit(1)
+	}
+	defer dbLock.Release()
+
+	// Subcommands like "c3 import" run without starting the TUI
+	if flag.NArg() > 0 {
+		e := env{dbPath: dbPath, readOnly: readOnly, backupPolicy: policy}
+		if err := runCommand(e, flag.Arg(0), flag.Args()[1:]); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			dbLock.Release()
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The passphrase has to be known before migrations or the TUI read the file
+	if err := unlockDatabase(dbPath); err != nil {
+		fmt.Printf("Error unlocking database: %v\n", err)
 		os.Exit(1)
 	}
+
+	if readOnly {
+		// Migrations and imports write, the lock holder takes care of them
+	} else if store.IsSQLite(dbPath) {
+		// A fresh sqlite database picks up the json database lying next to it
+		jsonPath := filepath.Join(dir, config.DB_FILE_NAME)
+		if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+			if _, err := os.Stat(jsonPath); err == nil {
+				fmt.Printf("Importing %s into %s...\n", jsonPath, dbPath)
+				if err := importJSON(dbPath, jsonPath); err != nil {
+					fmt.Printf("Error importing %s: %v\n", jsonPath, err)
+					os.Exit(1)
+				}
+			}
+		}
+	} else if err := migration.RunMigrations(dbPath); err != nil {
+		exitWithDBError("Error running migrations", dbPath, err)
+	}
+
+	var st store.Store
+	if readOnly {
+		st, err = store.OpenReadOnly(dbPath)
+	} else {
+		st, err = store.Open(dbPath)
+	}
+	if err != nil {
+		exitWithDBError("Error opening database", dbPath, err)
+	}
+	switch s := st.(type) {
+	case *store.JSONStore:
+		s.BeforeSave = func() error { return backup.Rotate(dbPath, policy) }
+	case *store.SQLiteStore:
+		// Sqlite never rewrites the whole file, one backup per session is enough
+		if _, err := backup.CreateWith(dbPath, "", s.BackupTo); err != nil {
+			fmt.Printf("Error creating backup: %v\n", err)
+			os.Exit(1)
+		}
+		if err := backup.Prune(dbPath, policy); err != nil {
+			fmt.Printf("Error pruning backups: %v\n", err)
+			os.Exit(1)
+		}
+	}
+	defer st.Close()
 	fmt.Println("Saving to:", dbPath)
-	p := tea.NewProgram(initialModel(dbPath), tea.WithAltScreen())
+	m := initialModel(st, readOnly)
+	if s, ok := st.(*store.JSONStore); ok && len(s.Problems) > 0 {
+		m.notice = fmt.Sprintf("Found %d problems in the database, see them with: c3 doctor", len(s.Problems))
+	}
+	p := tea.NewProgram(m, tea.WithAltScreen())
 	if _, err := p.Run(); err != nil {
 		fmt.Printf("Error: %v", err)
 		os.Exit(1)
 	}
 }
+
+// exitWithDBError prints err and, if the file is broken, how to get the data back
+func exitWithDBError(prefix, dbPath string, err error) {
+	fmt.Printf("%s: %v\n", prefix, err)
+	var syntaxErr *db.SyntaxError
+	if errors.As(err, &syntaxErr) {
+		fmt.Printf("\nThe database was not changed. Fix the file by hand or salvage what is still readable with:\n")
+		fmt.Printf("  c3 --db %s recover\n", dbPath)
+	}
+	os.Exit(1)
+}
+
+// reload picks up changes someone else made to the database file.
+// Selections are kept and open forms are checked for conflicting changes.
+func (m *model) reload() {
+	if err := m.store.(store.Reloader).Reload(); err != nil {
+		m.err = fmt.Errorf("reloading database: %w", err)
+		return
+	}
+	database, err := m.store.Snapshot()
+	if err != nil {
+		m.err = fmt.Errorf("reloading database: %w", err)
+		return
+	}
+	m.db = database
+	m.err = nil
+	m.notice = "The database was changed on disk and has been reloaded."
+
+	selectedID := ""
+	if p, ok := m.listPeople.SelectedItem().(person.Person); ok {
+		selectedID = p.ID
+	}
+	m.refreshPeopleList()
+	m.refreshFieldList()
+	m.refreshOverdueList()
+	m.refreshAgendaList()
+	m.refreshNetwork()
+	m.refreshGraph()
+	selectListItem(&m.listPeople, func(i list.Item) bool {
+		p, ok := i.(person.Person)
+		return ok && p.ID == selectedID
+	})
+
+	if m.state == viewFieldForm {
+		// Fields do not belong to the selected person, only the field matters
+		if m.editingField == nil {
+			return
+		}
+		if f, ok := db.FindField(m.db.Fields, m.editingField.Name); !ok {
+			m.conflict = "The field " + m.editingField.Name + " was deleted on disk. Saving will fail."
+		} else if !reflect.DeepEqual(f, *m.editingField) {
+			m.conflict = "The field " + m.editingField.Name + " was changed on disk while you were editing. Saving will overwrite those changes."
+		}
+		return
+	}
+	if m.selectedPerson == nil {
+		return
+	}
+	fresh, found := findPerson(m.db.People, m.selectedPerson.ID)
+
+	switch m.state {
+	case viewPersonForm, viewTagSelect:
+		// The form still works on the old version, only warn
+		if !m.isEditing {
+			return
+		}
+		if !found {
+			m.conflict = m.selectedPerson.Name + " was deleted on disk. Saving will fail."
+		} else if !reflect.DeepEqual(fresh, *m.selectedPerson) {
+			m.conflict = m.selectedPerson.Name + " was changed on disk while you were editing. Saving will overwrite those changes."
+		}
+
+	case viewRelationForm, viewRelationTarget:
+		if !found {
+			m.conflict = m.selectedPerson.Name + " was deleted on disk. Saving will fail."
+			return
+		}
+		if m.state == viewRelationForm && m.isEditing {
+			rel, ok := findRelation(m.db.Relations, m.selectedRel.ID)
+			if !ok {
+				m.conflict = "This connection was deleted on disk. Saving will fail."
+			} else if rel != *m.selectedRel {
+				m.conflict = "This connection was changed on disk while you were editing. Saving will overwrite those changes."
+			}
+		}
+
+	case viewInteractionForm, viewReminderForm:
+		// Both only add something new, it just needs the person to still be there
+		if !found {
+			m.conflict = m.selectedPerson.Name + " was deleted on disk. Saving will fail."
+			return
+		}
+		m.syncSelection()
+
+	default:
+		name := m.selectedPerson.Name
+		if !m.syncSelection() {
+			m.notice = name + " was deleted on disk."
+		}
+	}
+}
+
+// checkSelectedPerson fails if the selected person was deleted on disk
+// while a form that adds something for them was open
+func (m model) checkSelectedPerson() error {
+	if _, ok := findPerson(m.db.People, m.selectedPerson.ID); !ok {
+		return fmt.Errorf("your change was not saved: %s was deleted on disk", m.selectedPerson.Name)
+	}
+	return nil
+}
+
+// syncSelection points the selected person to the current snapshot and keeps
+// the selected connection. If the person is gone it goes back to the list and returns false.
+func (m *model) syncSelection() bool {
+	if m.selectedPerson == nil {
+		return true
+	}
+	fresh, found := findPerson(m.db.People, m.selectedPerson.ID)
+	if !found {
+		m.selectedPerson = nil
+		m.state = viewListPeople
+		return false
+	}
+	m.selectedPerson = &fresh
+	m.refreshHistory()
+
+	relID := ""
+	if r, ok := m.listRelations.SelectedItem().(relation.RelationItem); ok {
+		relID = r.Rel.ID
+	}
+	m.refreshRelationList()
+	selectListItem(&m.listRelations, func(i list.Item) bool {
+		r, ok := i.(relation.RelationItem)
+		return ok && r.Rel.ID == relID
+	})
+	return true
+}
+
+// selectListItem moves the cursor to the first visible item matching match
+func selectListItem(l *list.Model, match func(list.Item) bool) {
+	for i, it := range l.VisibleItems() {
+		if match(it) {
+			l.Select(i)
+			return
+		}
+	}
+}
+
+func findPerson(people []person.Person, id string) (person.Person, bool) {
+	for _, p := range people {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return person.Person{}, false
+}
+
+func findRelation(rels []relation.Relation, id string) (relation.Relation, bool) {
+	for _, r := range rels {
+		if r.ID == id {
+			return r, true
+		}
+	}
+	return relation.Relation{}, false
+}
+
+// refreshHistory loads the history of the selected person if the pane is open
+func (m *model) refreshHistory() {
+	if !m.showHistory || m.selectedPerson == nil {
+		return
+	}
+	events, err := m.store.ListEvents(m.selectedPerson.ID)
+	if err != nil {
+		m.err = fmt.Errorf("loading history: %w", err)
+		return
+	}
+	m.events = events
+}
+
+// historyView renders the newest events that fit where the connections list would be
+func (m model) historyView() string {
+	if len(m.events) == 0 {
+		return infoStyle.Render("(No changes recorded yet)")
+	}
+	lines := []string{}
+	for i := len(m.events) - 1; i >= 0 && len(lines) < m.listRelations.Height(); i-- {
+		e := m.events[i]
+		lines = append(lines, infoStyle.Render(e.Time.Format("2006-01-02 15:04"))+"  "+describeEvent(e))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// describeEvent turns an event into something like "Alice: notes "a" -> "b""
+func describeEvent(e db.Event) string {
+	subject := e.Label
+	switch e.Entity {
+	case db.EntityRelation:
+		subject = "Connection " + e.Label
+	case db.EntityField:
+		subject = "Field " + e.Label
+	}
+	switch e.Action {
+	case db.ActionCreate:
+		return subject + " created"
+	case db.ActionDelete:
+		return subject + " deleted"
+	}
+	return fmt.Sprintf("%s: %s %q -> %q", subject, e.Field, e.Old, e.New)
+}
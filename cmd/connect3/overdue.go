@@ -0,0 +1,116 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/cadence"
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/store"
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// overdueItem shows a person who is due for contact in the dashboard
+type overdueItem struct{ status cadence.Status }
+
+func (i overdueItem) Title() string { return i.status.Person.Name }
+func (i overdueItem) Description() string {
+	return describeStatus(i.status)
+}
+func (i overdueItem) FilterValue() string { return i.status.Person.Name }
+
+// describeStatus says how overdue someone is, e.g. "12 days overdue, last contact 2024-01-01, every 30 days"
+func describeStatus(s cadence.Status) string {
+	every := fmt.Sprintf("every %d days", s.Person.Cadence)
+	switch {
+	case s.Never():
+		return "never contacted, " + every
+	case s.Overdue < 0:
+		return fmt.Sprintf("due in %d days, last contact %s, %s", -s.Overdue, s.LastContact, every)
+	case s.Overdue == 0:
+		return fmt.Sprintf("due today, last contact %s, %s", s.LastContact, every)
+	}
+	return fmt.Sprintf("%d days overdue, last contact %s, %s", s.Overdue, s.LastContact, every)
+}
+
+func newOverdueList() list.Model {
+	l := list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0)
+	l.Title = "Keep in Touch"
+	l.SetFilteringEnabled(false)
+	l.DisableQuitKeybindings()
+	l.AdditionalShortHelpKeys = func() []key.Binding {
+		return []key.Binding{
+			key.NewBinding(key.WithKeys("enter"), key.WithHelp("enter", "Open")),
+			key.NewBinding(key.WithKeys("s"), key.WithHelp("s", fmt.Sprintf("Snooze %d Days", config.SNOOZE_DAYS))),
+			key.NewBinding(key.WithKeys("esc"), key.WithHelp("esc", "Back")),
+		}
+	}
+	return l
+}
+
+// refreshOverdueList shows who is due today, most overdue first
+func (m *model) refreshOverdueList() {
+	due := cadence.Overdue(m.db.People, m.db.Interactions, cadence.Today(time.Now()))
+	items := make([]list.Item, len(due))
+	for i, s := range due {
+		items[i] = overdueItem{status: s}
+	}
+	m.listOverdue.SetItems(items)
+	m.listOverdue.Title = fmt.Sprintf("Keep in Touch (%d due)", len(due))
+}
+
+func (m model) updateOverdue(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc", "backspace":
+			m.state = viewListPeople
+			return m, nil
+		case "enter":
+			if i, ok := m.listOverdue.SelectedItem().(overdueItem); ok {
+				p := i.status.Person
+				m.selectedPerson = &p
+				m.backTo = viewOverdue
+				m.state = viewDetail
+				m.refreshRelationList()
+				m.refreshHistory()
+			}
+			return m, nil
+		case "s":
+			if i, ok := m.listOverdue.SelectedItem().(overdueItem); ok {
+				p := i.status.Person
+				until := cadence.Today(time.Now()).AddDate(0, 0, config.SNOOZE_DAYS)
+				p.SnoozedUntil = until.Format(config.DATE_LAYOUT)
+				if m.do("Snooze "+p.Name, func(tx store.Tx) error { return tx.UpdatePerson(p) }) {
+					m.notice = p.Name + " is snoozed until " + p.SnoozedUntil + "."
+				}
+			}
+			return m, nil
+		case "u":
+			m.undoRedo(false)
+			return m, nil
+		case "ctrl+r":
+			m.undoRedo(true)
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.listOverdue, cmd = m.listOverdue.Update(msg)
+	return m, cmd
+}
+
+// cadenceView renders the keep in touch status of p for the detail view
+func (m model) cadenceView(p person.Person) string {
+	today := cadence.Today(time.Now())
+	s, ok := cadence.Of(p, cadence.LastContacts(m.db.Interactions), today)
+	if !ok {
+		return ""
+	}
+	line := infoStyle.Render("Keep in touch: ") + describeStatus(s)
+	if p.Snoozed(today) {
+		line += infoStyle.Render(" (snoozed until " + p.SnoozedUntil + ")")
+	}
+	return line + "\n\n"
+}
@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/N3moAhead/connect3/internal/backup"
+	"github.com/N3moAhead/connect3/internal/crypt"
+	"github.com/N3moAhead/connect3/internal/fileutil"
+	"github.com/N3moAhead/connect3/internal/store"
+	"github.com/charmbracelet/x/term"
+)
+
+// PASSPHRASE_ENV lets scripts pass the passphrase without a prompt
+const PASSPHRASE_ENV = "C3_PASSPHRASE"
+
+const passphraseAttempts = 3
+
+// readPassphrase asks for a passphrase without echoing it.
+// Without a terminal it reads a line from stdin instead.
+func readPassphrase(prompt string) ([]byte, error) {
+	if p, ok := os.LookupEnv(PASSPHRASE_ENV); ok {
+		return []byte(p), nil
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	if term.IsTerminal(os.Stdin.Fd()) {
+		p, err := term.ReadPassword(os.Stdin.Fd())
+		fmt.Fprintln(os.Stderr)
+		return p, err
+	}
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return nil, err
+	}
+	return []byte(strings.TrimRight(line, "\r\n")), nil
+}
+
+// unlockDatabase asks for the passphrase if the database is encrypted
+// and checks it against the file before anything else reads it
+func unlockDatabase(dbPath string) error {
+	if store.IsSQLite(dbPath) {
+		return nil
+	}
+	content, err := os.ReadFile(dbPath)
+	if os.IsNotExist(err) || !crypt.IsEncrypted(content) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	for attempt := 1; ; attempt++ {
+		p, err := readPassphrase(fmt.Sprintf("Passphrase for %s: ", dbPath))
+		if err != nil {
+			return err
+		}
+		_, err = crypt.Decrypt(content, p)
+		if err == nil {
+			crypt.SetPassphrase(p)
+			return nil
+		}
+		_, fromEnv := os.LookupEnv(PASSPHRASE_ENV)
+		if !errors.Is(err, crypt.ErrWrongPassphrase) || fromEnv || attempt == passphraseAttempts {
+			return err
+		}
+		fmt.Fprintln(os.Stderr, "Wrong passphrase, try again.")
+	}
+}
+
+// newPassphrase asks for a passphrase twice
+func newPassphrase() ([]byte, error) {
+	p, err := readPassphrase("New passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if len(p) == 0 {
+		return nil, fmt.Errorf("the passphrase must not be empty")
+	}
+	if _, fromEnv := os.LookupEnv(PASSPHRASE_ENV); fromEnv {
+		return p, nil
+	}
+	again, err := readPassphrase("Repeat passphrase: ")
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(p, again) {
+		return nil, fmt.Errorf("the passphrases do not match")
+	}
+	return p, nil
+}
+
+// runEncrypt converts a plain json database into an encrypted one.
+// The backups are converted as well, otherwise they would keep a readable copy.
+func runEncrypt(e env) error {
+	if err := e.writable(); err != nil {
+		return err
+	}
+	if store.IsSQLite(e.dbPath) {
+		return fmt.Errorf("encryption only works on json databases")
+	}
+	content, err := os.ReadFile(e.dbPath)
+	if err != nil {
+		return err
+	}
+	if crypt.IsEncrypted(content) {
+		return fmt.Errorf("%s is already encrypted", e.dbPath)
+	}
+	p, err := newPassphrase()
+	if err != nil {
+		return err
+	}
+	sealed, err := crypt.Encrypt(content, p)
+	if err != nil {
+		return err
+	}
+	if err := fileutil.WriteAtomic(e.dbPath, sealed, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Encrypted %s\n", e.dbPath)
+	return convertBackups(e.dbPath, func(content []byte) ([]byte, error) {
+		if crypt.IsEncrypted(content) {
+			return nil, nil
+		}
+		return crypt.Encrypt(content, p)
+	})
+}
+
+// runDecrypt turns an encrypted json database and its backups back into plain json
+func runDecrypt(e env) error {
+	if err := e.writable(); err != nil {
+		return err
+	}
+	if store.IsSQLite(e.dbPath) {
+		return fmt.Errorf("encryption only works on json databases")
+	}
+	if err := unlockDatabase(e.dbPath); err != nil {
+		return err
+	}
+	if !crypt.Enabled() {
+		return fmt.Errorf("%s is not encrypted", e.dbPath)
+	}
+	content, err := crypt.ReadFile(e.dbPath)
+	if err != nil {
+		return err
+	}
+	if err := fileutil.WriteAtomic(e.dbPath, content, 0644); err != nil {
+		return err
+	}
+	fmt.Printf("Decrypted %s\n", e.dbPath)
+	return convertBackups(e.dbPath, func(content []byte) ([]byte, error) {
+		if !crypt.IsEncrypted(content) {
+			return nil, nil
+		}
+		return crypt.Open(content)
+	})
+}
+
+// convertBackups rewrites every backup of dbPath with convert.
+// convert returns nil for backups that need no change. A backup that
+// fails to convert (e.g. one encrypted with an older passphrase) is reported and left alone.
+func convertBackups(dbPath string, convert func(content []byte) ([]byte, error)) error {
+	backups, err := backup.List(dbPath)
+	if err != nil {
+		return err
+	}
+	converted := 0
+	for _, b := range backups {
+		content, err := os.ReadFile(b.Path)
+		if err != nil {
+			return err
+		}
+		out, err := convert(content)
+		if err != nil {
+			fmt.Printf("Skipping backup %s: %v\n", b.ID, err)
+			continue
+		}
+		if out == nil {
+			continue
+		}
+		if err := fileutil.WriteAtomic(b.Path, out, 0600); err != nil {
+			return err
+		}
+		converted++
+	}
+	if converted > 0 {
+		fmt.Printf("Converted %d backups\n", converted)
+	}
+	return nil
+}
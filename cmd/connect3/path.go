@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/N3moAhead/connect3/internal/graph"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// pathResult is what the path view shows, found by findPaths
+type pathResult struct {
+	from, to  person.Person
+	shortest  graph.Path
+	strongest graph.Path
+	found     bool
+}
+
+// findPaths looks for the path with the fewest hops and the one over the strongest ties
+func findPaths(ix *graph.Index, from, to person.Person) pathResult {
+	res := pathResult{from: from, to: to}
+	res.shortest, res.found = ix.ShortestPath(from.ID, to.ID)
+	res.strongest, _ = ix.StrongestPath(from.ID, to.ID)
+	return res
+}
+
+// describeHops renders each hop of p as "Ada → Grace (4/5, Colleagues)",
+// the arrow points the way the relation was added
+func describeHops(people []person.Person, p graph.Path) []string {
+	lines := []string{}
+	for i, r := range p.Hops {
+		arrow := "→"
+		if r.FromID != p.People[i] {
+			arrow = "←"
+		}
+		line := fmt.Sprintf("%s %s %s (%d/5", getName(people, p.People[i]), arrow, getName(people, p.People[i+1]), r.Strength)
+		if r.Description != "" {
+			line += ", " + r.Description
+		}
+		lines = append(lines, line+")")
+	}
+	return lines
+}
+
+// pathSections titles the paths of res, just one if both ways agree
+func pathSections(res pathResult) (titles []string, paths []graph.Path) {
+	hops := func(p graph.Path) string {
+		if len(p.Hops) == 1 {
+			return "1 hop"
+		}
+		return fmt.Sprintf("%d hops", len(p.Hops))
+	}
+	if slices.Equal(res.shortest.People, res.strongest.People) {
+		return []string{fmt.Sprintf("Shortest and strongest path (%s, cost %d)", hops(res.shortest), res.shortest.Cost)},
+			[]graph.Path{res.shortest}
+	}
+	return []string{
+			fmt.Sprintf("Fewest hops (%s, cost %d)", hops(res.shortest), res.shortest.Cost),
+			fmt.Sprintf("Strongest ties (%s, cost %d)", hops(res.strongest), res.strongest.Cost),
+		},
+		[]graph.Path{res.shortest, res.strongest}
+}
+
+// openPathTarget lets the user pick who to find a path to from the selected person
+func (m *model) openPathTarget() {
+	m.state = viewPathTarget
+	m.listPeople.Title = "Find a path from " + m.selectedPerson.Name + " to"
+	m.listPeople.ResetSelected()
+}
+
+func (m model) updatePathTarget(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok && m.listPeople.FilterState() != list.Filtering {
+		switch msg.String() {
+		case "esc":
+			m.state = viewDetail
+			m.refreshPeopleList()
+			return m, nil
+		case "enter":
+			if i, ok := m.listPeople.SelectedItem().(person.Person); ok {
+				if i.ID == m.selectedPerson.ID {
+					return m, nil
+				}
+				m.path = findPaths(m.network, *m.selectedPerson, i)
+				m.state = viewPath
+				m.refreshPeopleList()
+			}
+			return m, nil
+		}
+	}
+	var cmd tea.Cmd
+	m.listPeople, cmd = m.listPeople.Update(msg)
+	return m, cmd
+}
+
+func (m model) updatePath(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc", "backspace":
+			m.state = viewDetail
+		case "p":
+			m.openPathTarget()
+		}
+	}
+	return m, nil
+}
+
+func (m model) pathView() string {
+	s := titleStyle.Render("From "+m.path.from.Name+" to "+m.path.to.Name) + "\n\n"
+	if !m.path.found {
+		s += m.path.from.Name + " and " + m.path.to.Name + " are not connected, not even through others.\n\n"
+	} else {
+		titles, paths := pathSections(m.path)
+		for i, p := range paths {
+			s += infoStyle.Render(titles[i]+":") + "\n"
+			s += strings.Join(describeHops(m.db.People, p), "\n") + "\n\n"
+		}
+	}
+	return s + infoStyle.Render("p: Another Path | ESC: Back")
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/reminder"
+	"github.com/N3moAhead/connect3/internal/store"
+	"github.com/charmbracelet/bubbles/textinput"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/google/uuid"
+)
+
+// Inputs of the reminder form in model.inputReminder, in tab order.
+// Enter on the last one saves the form.
+const (
+	reminderDate = iota
+	reminderText
+	reminderRepeat
+)
+
+func newReminderInputs() []textinput.Model {
+	placeholders := []string{
+		"2024-12-31",
+		"What to do, e.g. Ask how the move went",
+		"Empty for once, " + reminder.RepeatYearly + " for anniversaries",
+	}
+	inputs := make([]textinput.Model, len(placeholders))
+	for i, p := range placeholders {
+		inputs[i] = textinput.New()
+		inputs[i].Placeholder = p
+		inputs[i].Width = 50
+	}
+	return inputs
+}
+
+// openReminderForm starts a new reminder for the selected person, due today
+func (m *model) openReminderForm() {
+	values := []string{time.Now().Format(config.DATE_LAYOUT), "", ""}
+	for i, v := range values {
+		m.inputReminder[i].SetValue(v)
+	}
+	m.state = viewReminderForm
+	m.focusReminderForm(reminderText)
+}
+
+func (m *model) focusReminderForm(i int) {
+	m.reminderFocus = i
+	for j := range m.inputReminder {
+		m.inputReminder[j].Blur()
+	}
+	m.inputReminder[i].Focus()
+}
+
+func (m model) updateReminderForm(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		last := len(m.inputReminder) - 1
+		switch msg.String() {
+		case "esc":
+			m.conflict = ""
+			m.state = viewDetail
+			return m, nil
+		case "tab":
+			m.focusReminderForm((m.reminderFocus + 1) % (last + 1))
+			return m, nil
+		case "shift+tab":
+			m.focusReminderForm((m.reminderFocus + last) % (last + 1))
+			return m, nil
+		case "enter":
+			if m.reminderFocus != last {
+				m.focusReminderForm(m.reminderFocus + 1)
+				return m, nil
+			}
+			r := reminder.Reminder{
+				ID:       uuid.New().String(),
+				PersonID: m.selectedPerson.ID,
+				Date:     strings.TrimSpace(m.inputReminder[reminderDate].Value()),
+				Text:     strings.TrimSpace(m.inputReminder[reminderText].Value()),
+				Repeat:   strings.ToLower(strings.TrimSpace(m.inputReminder[reminderRepeat].Value())),
+			}
+			err := r.Check()
+			if err == nil {
+				err = m.checkSelectedPerson()
+			}
+			if err != nil {
+				m.err = err
+				return m, nil
+			}
+			if !m.do("Add reminder for "+m.selectedPerson.Name, func(tx store.Tx) error { return tx.CreateReminder(r) }) {
+				return m, nil
+			}
+			m.state = viewDetail
+			return m, nil
+		}
+	}
+	cmds := make([]tea.Cmd, len(m.inputReminder))
+	for i := range m.inputReminder {
+		m.inputReminder[i], cmds[i] = m.inputReminder[i].Update(msg)
+	}
+	return m, tea.Batch(cmds...)
+}
+
+func (m model) reminderFormView() string {
+	labels := []string{"Date:", "Reminder:", "Repeat:"}
+	s := titleStyle.Render("Remind me about "+m.selectedPerson.Name) + "\n\n"
+	for i, label := range labels {
+		s += label + "\n" + m.inputReminder[i].View() + "\n\n"
+	}
+	return s + infoStyle.Render("Enter on Repeat to Save | Tab/Shift+Tab: Next/Previous Field | ESC: Back")
+}
+
+// remindersView renders the open reminders of the selected person for the detail view
+func (m model) remindersView() string {
+	open := []reminder.Reminder{}
+	for _, r := range m.db.Reminders {
+		if r.PersonID == m.selectedPerson.ID && !r.Done {
+			open = append(open, r)
+		}
+	}
+	if len(open) == 0 {
+		return ""
+	}
+	slices.SortStableFunc(open, func(a, b reminder.Reminder) int { return strings.Compare(a.Date, b.Date) })
+	s := ""
+	for _, r := range open {
+		line := infoStyle.Render("Reminder: ") + r.Date + " " + r.Text
+		if r.Repeat != reminder.RepeatNone {
+			line += infoStyle.Render(" (" + r.Repeat + ")")
+		}
+		if r.SnoozedUntil > r.Date {
+			line += infoStyle.Render(" (snoozed until " + r.SnoozedUntil + ")")
+		}
+		s += line + "\n"
+	}
+	return s + "\n"
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/graph"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/store"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Tags written by the stats, all of them start with autoTagPrefix and are
+// replaced every time the tags are written
+const (
+	autoTagPrefix      = "auto:"
+	autoTagHub         = autoTagPrefix + "hub"
+	autoTagBroker      = autoTagPrefix + "broker"
+	autoTagCommunity   = autoTagPrefix + "community-"
+	statsTop           = 5 // People listed per ranking and hubs tagged
+	statsGroupNames    = 8 // Names listed per component or community
+	statsMaxGroupLines = 5
+)
+
+// analyze computes the stats of the whole network
+func analyze(database db.Database) graph.Stats {
+	return graph.New(database.Relations).Analyze(personIDs(database.People))
+}
+
+// ranked returns the people with the highest score first, leaving out those at zero
+func ranked(s graph.Stats, score func(id string) float64) []string {
+	ids := []string{}
+	for _, id := range s.People {
+		if score(id) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	slices.SortStableFunc(ids, func(a, b string) int {
+		switch sa, sb := score(a), score(b); {
+		case sa > sb:
+			return -1
+		case sa < sb:
+			return 1
+		}
+		return 0
+	})
+	return ids[:min(len(ids), statsTop)]
+}
+
+// hubs are the best connected people, by the strength of all their relations
+func hubs(s graph.Stats) []string {
+	return ranked(s, func(id string) float64 { return float64(s.WeightedDegree[id]) })
+}
+
+// statsReport renders the stats for the stats view and c3 stats
+func statsReport(database db.Database, s graph.Stats) []string {
+	people := database.People
+	lines := []string{fmt.Sprintf("%d people, %s, %s, %s", len(s.People), plural(len(database.Relations), "relation"),
+		plural(len(s.Components), "component"), plural(len(s.Communities), "community"))}
+
+	lines = append(lines, "", "Hubs (strongest connected):")
+	for _, id := range hubs(s) {
+		lines = append(lines, fmt.Sprintf("  %-24s %s, strength %d", getName(people, id), plural(s.Degree[id], "connection"), s.WeightedDegree[id]))
+	}
+	lines = append(lines, "", "Brokers (on the most shortest paths between others):")
+	for _, id := range ranked(s, func(id string) float64 { return s.Betweenness[id] }) {
+		lines = append(lines, fmt.Sprintf("  %-24s %.1f", getName(people, id), s.Betweenness[id]))
+	}
+	if len(s.Articulation) > 0 {
+		lines = append(lines, "", "Without them the network falls apart:", "  "+groupNames(people, s.Articulation))
+	}
+	if len(s.Bridges) > 0 {
+		lines = append(lines, "", "Bridges (the only tie between two parts):")
+		for i, b := range s.Bridges {
+			if i == statsMaxGroupLines {
+				lines = append(lines, fmt.Sprintf("  ... and %d more", len(s.Bridges)-i))
+				break
+			}
+			lines = append(lines, "  "+getName(people, b[0])+" - "+getName(people, b[1]))
+		}
+	}
+	lines = append(lines, "", "Components:")
+	lines = append(lines, groupLines(people, s.Components)...)
+	lines = append(lines, "", "Communities:")
+	lines = append(lines, groupLines(people, s.Communities)...)
+	return lines
+}
+
+// plural counts n things, e.g. "1 relation" or "3 communities"
+func plural(n int, word string) string {
+	if n == 1 {
+		return "1 " + word
+	}
+	if strings.HasSuffix(word, "y") {
+		return fmt.Sprintf("%d %sies", n, strings.TrimSuffix(word, "y"))
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+// groupLines lists the biggest groups, people who are on their own are only counted
+func groupLines(people []person.Person, groups [][]string) []string {
+	lines := []string{}
+	alone := 0
+	for i, g := range groups {
+		if len(g) == 1 {
+			alone++
+			continue
+		}
+		if len(lines) == statsMaxGroupLines {
+			lines = append(lines, fmt.Sprintf("  ... and %d more", len(groups)-i))
+			break
+		}
+		lines = append(lines, fmt.Sprintf("  %d. %d people: %s", i+1, len(g), groupNames(people, g)))
+	}
+	if alone > 0 {
+		lines = append(lines, fmt.Sprintf("  %d people on their own", alone))
+	}
+	return lines
+}
+
+// groupNames names the first few people of a group
+func groupNames(people []person.Person, ids []string) string {
+	s := namesOf(people, ids[:min(len(ids), statsGroupNames)])
+	if len(ids) > statsGroupNames {
+		s += fmt.Sprintf(" and %d more", len(ids)-statsGroupNames)
+	}
+	return s
+}
+
+// autoTags works out the auto tags of everyone: hubs, brokers who hold
+// the network together and the community of everyone not on their own
+func autoTags(s graph.Stats) map[string][]string {
+	tags := map[string][]string{}
+	for _, id := range hubs(s) {
+		tags[id] = append(tags[id], autoTagHub)
+	}
+	for _, id := range s.Articulation {
+		tags[id] = append(tags[id], autoTagBroker)
+	}
+	for i, c := range s.Communities {
+		if len(c) < 2 {
+			continue
+		}
+		for _, id := range c {
+			tags[id] = append(tags[id], fmt.Sprintf("%s%d", autoTagCommunity, i+1))
+		}
+	}
+	return tags
+}
+
+// writeAutoTags replaces the auto tags of everyone, it reports how many people changed
+func writeAutoTags(tx store.Tx, tags map[string][]string) (int, error) {
+	people, err := tx.ListPeople()
+	if err != nil {
+		return 0, err
+	}
+	changed := 0
+	for _, p := range people {
+		newTags := slices.DeleteFunc(slices.Clone(p.Tags), func(t string) bool { return strings.HasPrefix(t, autoTagPrefix) })
+		newTags = append(newTags, tags[p.ID]...)
+		if newTags == nil {
+			newTags = []string{}
+		}
+		if slices.Equal(newTags, p.Tags) {
+			continue
+		}
+		p.Tags = newTags
+		if err := tx.UpdatePerson(p); err != nil {
+			return changed, err
+		}
+		changed++
+	}
+	return changed, nil
+}
+
+// openStats analyzes the network once, betweenness is too slow to redo on every key
+func (m *model) openStats() {
+	m.stats = analyze(m.db)
+	m.state = viewStats
+}
+
+func (m model) updateStats(msg tea.Msg) (tea.Model, tea.Cmd) {
+	if msg, ok := msg.(tea.KeyMsg); ok {
+		switch msg.String() {
+		case "esc", "backspace":
+			m.state = viewListPeople
+		case "T":
+			tags := autoTags(m.stats)
+			changed := 0
+			if m.do("Write auto tags", func(tx store.Tx) (err error) {
+				changed, err = writeAutoTags(tx, tags)
+				return err
+			}) {
+				m.notice = fmt.Sprintf("Updated the %s tags of %d people.", autoTagPrefix, changed)
+			}
+		case "u":
+			m.undoRedo(false)
+		case "ctrl+r":
+			m.undoRedo(true)
+		case "r":
+			m.stats = analyze(m.db)
+		}
+	}
+	return m, nil
+}
+
+func (m model) statsView() string {
+	s := titleStyle.Render("Network Stats") + "\n\n"
+	s += strings.Join(statsReport(m.db, m.stats), "\n") + "\n\n"
+	return s + infoStyle.Render("T: Write "+autoTagPrefix+" Tags | r: Recompute | u/Ctrl+r: Undo/Redo | ESC: Back")
+}
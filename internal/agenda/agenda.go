@@ -0,0 +1,129 @@
+package agenda
+
+import (
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/reminder"
+)
+
+// Kinds of entries, only reminders and follow-ups can be completed or snoozed
+const (
+	KindReminder = "reminder"
+	KindBirthday = "birthday"
+	KindFollowUp = "follow up"
+)
+
+// Sections the agenda is grouped into
+const (
+	SectionOverdue = "Overdue"
+	SectionToday   = "Today"
+	SectionWeek    = "This week"
+)
+
+// Entry is one thing due about a person
+type Entry struct {
+	Kind   string
+	ID     string // Reminder or interaction, empty for birthdays
+	Date   time.Time
+	Person person.Person // For follow-ups the first participant
+	Text   string
+}
+
+// Days until the entry is due, negative if it is overdue
+func (e Entry) Days(today time.Time) int {
+	return int(e.Date.Sub(today).Hours() / 24)
+}
+
+// Section says which part of the agenda the entry belongs to
+func (e Entry) Section(today time.Time) string {
+	switch days := e.Days(today); {
+	case days < 0:
+		return SectionOverdue
+	case days == 0:
+		return SectionToday
+	}
+	return SectionWeek
+}
+
+// Build lists what is due up to days after today, oldest first. Open reminders and
+// follow-ups stay on it until they are done, birthdays only show up ahead of time.
+func Build(database db.Database, today time.Time, days int) []Entry {
+	end := today.AddDate(0, 0, days)
+	people := map[string]person.Person{}
+	for _, p := range database.People {
+		people[p.ID] = p
+	}
+
+	entries := []Entry{}
+	for _, r := range database.Reminders {
+		p, ok := people[r.PersonID]
+		date, err := r.Due()
+		if !ok || err != nil || r.Done || date.After(end) {
+			continue
+		}
+		entries = append(entries, Entry{Kind: KindReminder, ID: r.ID, Date: date, Person: p, Text: r.Text})
+	}
+
+	for _, p := range database.People {
+		date, age, ok := NextBirthday(p, today)
+		if !ok || date.After(end) {
+			continue
+		}
+		text := "Birthday"
+		if age > 0 {
+			text += " (" + strconv.Itoa(age) + ")"
+		}
+		entries = append(entries, Entry{Kind: KindBirthday, Date: date, Person: p, Text: text})
+	}
+
+	for _, in := range database.Interactions {
+		if in.FollowUp == "" || len(in.Participants) == 0 {
+			continue
+		}
+		p, ok := people[in.Participants[0]]
+		date, err := interaction.ParseDate(in.FollowUp)
+		if !ok || err != nil || date.After(end) {
+			continue
+		}
+		text := "Follow up on the " + in.Kind + " of " + in.Date
+		if in.Summary != "" {
+			text += ": " + in.Summary
+		}
+		entries = append(entries, Entry{Kind: KindFollowUp, ID: in.ID, Date: date, Person: p, Text: text})
+	}
+
+	slices.SortStableFunc(entries, func(a, b Entry) int {
+		if c := a.Date.Compare(b.Date); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Person.Name, b.Person.Name)
+	})
+	return entries
+}
+
+// NextBirthday returns the next birthday of p on or after today and the age p turns,
+// which is 0 if the year is unknown. ok is false if p has no valid birthday.
+func NextBirthday(p person.Person, today time.Time) (date time.Time, age int, ok bool) {
+	if p.Birthday == "" {
+		return date, 0, false
+	}
+	year, month, day, err := person.ParseBirthday(p.Birthday)
+	if err != nil {
+		return date, 0, false
+	}
+	// Feb 29 falls on Feb 28 in other years, like yearly reminders
+	date = reminder.Anniversary(today.Year(), month, day, time.UTC)
+	if date.Before(today) {
+		date = reminder.Anniversary(today.Year()+1, month, day, time.UTC)
+	}
+	if year > 0 {
+		age = date.Year() - year
+	}
+	return date, age, true
+}
@@ -0,0 +1,58 @@
+package agenda
+
+import (
+	"testing"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/reminder"
+)
+
+func day(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(config.DATE_LAYOUT, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestNextBirthday(t *testing.T) {
+	tests := []struct {
+		name, birthday, today string
+		want                  string
+		age                   int
+	}{
+		{"later this year", "1990-06-01", "2024-03-01", "2024-06-01", 34},
+		{"today", "1990-06-01", "2024-06-01", "2024-06-01", 34},
+		{"next year", "1990-06-01", "2024-06-02", "2025-06-01", 35},
+		{"leap day", "2000-02-29", "2024-01-01", "2024-02-29", 24},
+		// Like yearly reminders, not on Mar 1
+		{"leap day in a common year", "2000-02-29", "2025-01-01", "2025-02-28", 25},
+		{"leap day just passed", "2000-02-29", "2025-03-01", "2026-02-28", 26},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			date, age, ok := NextBirthday(person.Person{Birthday: tt.birthday}, day(t, tt.today))
+			if !ok || date.Format(config.DATE_LAYOUT) != tt.want || age != tt.age {
+				t.Fatalf("got %s (%d, ok %v), want %s (%d)", date.Format(config.DATE_LAYOUT), age, ok, tt.want, tt.age)
+			}
+		})
+	}
+}
+
+func TestBuildShowsSnoozedReminders(t *testing.T) {
+	database := db.Database{
+		People: []person.Person{{ID: "p1", Name: "Ada"}},
+		Reminders: []reminder.Reminder{
+			{ID: "m1", PersonID: "p1", Date: "2024-06-01", SnoozedUntil: "2024-06-11"},
+			{ID: "m2", PersonID: "p1", Date: "2024-06-01", SnoozedUntil: "2024-07-01"},
+		},
+	}
+	entries := Build(database, day(t, "2024-06-10"), 7)
+	if len(entries) != 1 || entries[0].ID != "m1" || entries[0].Date.Format(config.DATE_LAYOUT) != "2024-06-11" {
+		t.Fatalf("got %+v, want only m1 on 2024-06-11", entries)
+	}
+}
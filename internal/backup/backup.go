@@ -0,0 +1,245 @@
+package backup
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/fileutil"
+)
+
+const (
+	DIR_NAME = "backups"
+	// Backup IDs are this timestamp without the dot, so they sort by name
+	idLayout = "20060102-150405.000"
+	idLength = len(idLayout) - 1
+)
+
+type Backup struct {
+	ID    string // Timestamp, used to restore the backup
+	Label string // Set for backups that are kept forever, e.g. before a migration
+	Path  string
+	Time  time.Time
+	Size  int64
+}
+
+// Policy says which backups survive pruning.
+// A backup is kept if any of the rules wants to keep it.
+type Policy struct {
+	Last   int // The newest n backups
+	Daily  int // The newest backup of each of the last n days
+	Weekly int // The newest backup of each of the last n weeks
+}
+
+// Dir is the backup directory next to the database
+func Dir(dbPath string) string {
+	return filepath.Join(filepath.Dir(dbPath), DIR_NAME)
+}
+
+// splitName returns "data" and ".json" for "data.json"
+func splitName(dbPath string) (string, string) {
+	base := filepath.Base(dbPath)
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext), ext
+}
+
+// NewPath returns a fresh backup file name for dbPath
+// and makes sure the backup directory exists
+func NewPath(dbPath, label string) (string, error) {
+	if err := os.MkdirAll(Dir(dbPath), 0755); err != nil {
+		return "", err
+	}
+	stem, ext := splitName(dbPath)
+	name := stem + "-" + formatID(time.Now())
+	if label != "" {
+		name += "-" + label
+	}
+	return filepath.Join(Dir(dbPath), name+ext), nil
+}
+
+func formatID(t time.Time) string {
+	return strings.Replace(t.Format(idLayout), ".", "", 1)
+}
+
+func parseID(id string) (time.Time, error) {
+	if len(id) != idLength {
+		return time.Time{}, fmt.Errorf("invalid backup id %q", id)
+	}
+	dot := strings.Index(idLayout, ".")
+	return time.ParseInLocation(idLayout, id[:dot]+"."+id[dot:], time.Local)
+}
+
+// Create copies the database into the backup directory.
+// Nothing happens if there is no database yet.
+func Create(dbPath, label string) (*Backup, error) {
+	return CreateWith(dbPath, label, func(dst string) error {
+		content, err := os.ReadFile(dbPath)
+		if err != nil {
+			return err
+		}
+		return fileutil.WriteAtomic(dst, content, 0600)
+	})
+}
+
+// CreateWith is Create for databases that have to write the copy themselves,
+// like sqlite where the file alone misses what is still in the journal
+func CreateWith(dbPath, label string, write func(dst string) error) (*Backup, error) {
+	if _, err := os.Stat(dbPath); os.IsNotExist(err) {
+		return nil, nil
+	}
+	dst, err := NewPath(dbPath, label)
+	if err != nil {
+		return nil, err
+	}
+	if err := write(dst); err != nil {
+		return nil, fmt.Errorf("creating backup: %w", err)
+	}
+	b, ok := parse(dbPath, dst)
+	if !ok {
+		return nil, fmt.Errorf("creating backup: unexpected file name %s", dst)
+	}
+	return &b, nil
+}
+
+// Rotate creates a backup and prunes the old ones
+func Rotate(dbPath string, policy Policy) error {
+	if _, err := Create(dbPath, ""); err != nil {
+		return err
+	}
+	return Prune(dbPath, policy)
+}
+
+// parse turns a file in the backup directory back into a Backup
+func parse(dbPath, path string) (Backup, bool) {
+	stem, ext := splitName(dbPath)
+	name := filepath.Base(path)
+	if !strings.HasPrefix(name, stem+"-") || !strings.HasSuffix(name, ext) {
+		return Backup{}, false
+	}
+	rest := strings.TrimSuffix(strings.TrimPrefix(name, stem+"-"), ext)
+	if len(rest) < idLength {
+		return Backup{}, false
+	}
+	id := rest[:idLength]
+	t, err := parseID(id)
+	if err != nil {
+		return Backup{}, false
+	}
+	b := Backup{ID: id, Path: path, Time: t, Label: strings.TrimPrefix(rest[idLength:], "-")}
+	if info, err := os.Stat(path); err == nil {
+		b.Size = info.Size()
+	}
+	return b, true
+}
+
+// List returns all backups of dbPath, newest first
+func List(dbPath string) ([]Backup, error) {
+	entries, err := os.ReadDir(Dir(dbPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	backups := []Backup{}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if b, ok := parse(dbPath, filepath.Join(Dir(dbPath), e.Name())); ok {
+			backups = append(backups, b)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].Time.After(backups[j].Time)
+	})
+	return backups, nil
+}
+
+// Find returns the backup with the given ID. A unique prefix is enough.
+func Find(dbPath, id string) (Backup, error) {
+	backups, err := List(dbPath)
+	if err != nil {
+		return Backup{}, err
+	}
+	var found []Backup
+	for _, b := range backups {
+		if b.ID == id {
+			return b, nil
+		}
+		if strings.HasPrefix(b.ID, id) {
+			found = append(found, b)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return Backup{}, fmt.Errorf("no backup with id %s", id)
+	case 1:
+		return found[0], nil
+	}
+	return Backup{}, fmt.Errorf("id %s matches %d backups", id, len(found))
+}
+
+// Restore puts a backup in place of the database.
+// The current database is backed up first so a restore can be undone.
+func Restore(dbPath, id string) (Backup, error) {
+	b, err := Find(dbPath, id)
+	if err != nil {
+		return b, err
+	}
+	content, err := os.ReadFile(b.Path)
+	if err != nil {
+		return b, err
+	}
+	if _, err := Create(dbPath, "pre-restore"); err != nil {
+		return b, err
+	}
+	// Stale sqlite journals would be replayed onto the restored file
+	for _, suffix := range []string{"-wal", "-shm"} {
+		if err := os.Remove(dbPath + suffix); err != nil && !os.IsNotExist(err) {
+			return b, err
+		}
+	}
+	return b, fileutil.WriteAtomic(dbPath, content, 0644)
+}
+
+// Prune deletes the backups the policy does not want to keep.
+// Labeled backups are never pruned.
+func Prune(dbPath string, policy Policy) error {
+	backups, err := List(dbPath)
+	if err != nil {
+		return err
+	}
+	days := map[string]bool{}
+	weeks := map[string]bool{}
+	n := 0
+	for _, b := range backups {
+		if b.Label != "" {
+			continue
+		}
+		keep := n < policy.Last
+		n++
+
+		day := b.Time.Format("2006-01-02")
+		if !days[day] && len(days) < policy.Daily {
+			days[day] = true
+			keep = true
+		}
+		year, w := b.Time.ISOWeek()
+		week := fmt.Sprintf("%d-%d", year, w)
+		if !weeks[week] && len(weeks) < policy.Weekly {
+			weeks[week] = true
+			keep = true
+		}
+
+		if !keep {
+			if err := os.Remove(b.Path); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
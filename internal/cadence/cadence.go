@@ -0,0 +1,78 @@
+package cadence
+
+import (
+	"slices"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/person"
+)
+
+// Status is where a person with a cadence stands on a given day
+type Status struct {
+	Person      person.Person
+	LastContact string // Date of the newest interaction, empty if there never was one
+	Due         time.Time
+	Overdue     int // Days since Due, negative if it is still ahead
+}
+
+// Never reports if there was no contact yet, those are due right away
+func (s Status) Never() bool {
+	return s.LastContact == ""
+}
+
+// Today is the date of now, comparable with the parsed dates of the database
+func Today(now time.Time) time.Time {
+	y, m, d := now.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// LastContacts returns the date of the newest interaction of everyone who had one
+func LastContacts(interactions []interaction.Interaction) map[string]string {
+	last := map[string]string{}
+	for _, in := range interactions {
+		for _, id := range in.Participants {
+			if in.Date > last[id] {
+				last[id] = in.Date
+			}
+		}
+	}
+	return last
+}
+
+// Of computes the status of p, ok is false if p has no cadence
+func Of(p person.Person, last map[string]string, today time.Time) (s Status, ok bool) {
+	if p.Cadence <= 0 {
+		return s, false
+	}
+	s = Status{Person: p, LastContact: last[p.ID], Due: today}
+	if t, err := interaction.ParseDate(s.LastContact); err == nil {
+		s.Due = t.AddDate(0, 0, p.Cadence)
+	}
+	s.Overdue = int(today.Sub(s.Due).Hours() / 24)
+	return s, true
+}
+
+// Overdue lists everyone who is due by today and not snoozed, most overdue first.
+// People who were never contacted come first, there is no telling how long they waited.
+func Overdue(people []person.Person, interactions []interaction.Interaction, today time.Time) []Status {
+	last := LastContacts(interactions)
+	due := []Status{}
+	for _, p := range people {
+		s, ok := Of(p, last, today)
+		if !ok || s.Overdue < 0 || p.Snoozed(today) {
+			continue
+		}
+		due = append(due, s)
+	}
+	slices.SortStableFunc(due, func(a, b Status) int {
+		if a.Never() != b.Never() {
+			if a.Never() {
+				return -1
+			}
+			return 1
+		}
+		return b.Overdue - a.Overdue
+	})
+	return due
+}
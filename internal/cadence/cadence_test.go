@@ -0,0 +1,123 @@
+package cadence
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/person"
+)
+
+func day(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(config.DATE_LAYOUT, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func talk(date string, ids ...string) interaction.Interaction {
+	return interaction.Interaction{ID: date, Date: date, Kind: interaction.KindCall, Participants: ids}
+}
+
+func TestLastContacts(t *testing.T) {
+	last := LastContacts([]interaction.Interaction{
+		talk("2024-05-01", "p1", "p2"),
+		talk("2024-03-01", "p1"), // Older ones listed later do not count
+		talk("2024-05-20", "p2"),
+	})
+	if last["p1"] != "2024-05-01" || last["p2"] != "2024-05-20" || len(last) != 2 {
+		t.Fatalf("got %v", last)
+	}
+}
+
+func TestOf(t *testing.T) {
+	today := day(t, "2024-06-10")
+	last := map[string]string{"p1": "2024-05-01"}
+	tests := []struct {
+		name    string
+		p       person.Person
+		ok      bool
+		due     string
+		overdue int
+	}{
+		{"no cadence", person.Person{ID: "p1"}, false, "", 0},
+		{"overdue", person.Person{ID: "p1", Cadence: 30}, true, "2024-05-31", 10},
+		{"due today", person.Person{ID: "p1", Cadence: 40}, true, "2024-06-10", 0},
+		{"ahead", person.Person{ID: "p1", Cadence: 45}, true, "2024-06-15", -5},
+		// Never contacted is due right away
+		{"never", person.Person{ID: "p2", Cadence: 30}, true, "2024-06-10", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := Of(tt.p, last, today)
+			if ok != tt.ok {
+				t.Fatalf("ok is %v", ok)
+			}
+			if !ok {
+				return
+			}
+			if got := s.Due.Format(config.DATE_LAYOUT); got != tt.due || s.Overdue != tt.overdue {
+				t.Fatalf("got due %s, %d days overdue, want %s, %d", got, s.Overdue, tt.due, tt.overdue)
+			}
+		})
+	}
+}
+
+func ids(due []Status) []string {
+	list := []string{}
+	for _, s := range due {
+		list = append(list, s.Person.ID)
+	}
+	return list
+}
+
+func TestOverdueOrder(t *testing.T) {
+	people := []person.Person{
+		{ID: "a", Cadence: 30},  // 10 days overdue
+		{ID: "b", Cadence: 7},   // 33 days overdue
+		{ID: "c", Cadence: 60},  // Not due yet
+		{ID: "d"},               // No cadence
+		{ID: "e", Cadence: 90},  // Never contacted
+		{ID: "f", Cadence: 30},  // 10 days overdue, like a
+		{ID: "g", Cadence: 365}, // Never contacted either
+	}
+	interactions := []interaction.Interaction{talk("2024-05-01", "a", "b", "c", "d", "f")}
+	got := ids(Overdue(people, interactions, day(t, "2024-06-10")))
+	// Never contacted first, then the most overdue, ties keep their order
+	if want := []string{"e", "g", "b", "a", "f"}; !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestOverdueSnooze(t *testing.T) {
+	today := day(t, "2024-06-10")
+	interactions := []interaction.Interaction{talk("2024-05-01", "p1")}
+	tests := []struct {
+		name  string
+		until string
+		shown bool
+	}{
+		{"not snoozed", "", true},
+		{"snoozed", "2024-06-17", false},
+		{"snooze ends today", "2024-06-10", true},
+		{"snooze ended", "2024-06-01", true},
+		{"broken snooze", "next week", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := person.Person{ID: "p1", Cadence: 30, SnoozedUntil: tt.until}
+			due := Overdue([]person.Person{p}, interactions, today)
+			if (len(due) == 1) != tt.shown {
+				t.Fatalf("got %v, want shown %v", ids(due), tt.shown)
+			}
+			// A snooze hides the person, it does not move the due date
+			if tt.shown && due[0].Overdue != 10 {
+				t.Fatalf("got %d days overdue, want 10", due[0].Overdue)
+			}
+		})
+	}
+}
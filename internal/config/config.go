@@ -1,6 +1,27 @@
 package config
 
 const (
-	DB_FORMAT_VERSION = "1.0.0"
+	DB_FORMAT_VERSION = "1.6.0"
 	DB_FILE_NAME      = "data.json"
+
+	// Layout of every date in the database: interactions, reminders, snoozes and date fields
+	DATE_LAYOUT = "2006-01-02"
+
+	// Default backup retention, see backup.Policy
+	BACKUP_KEEP_LAST   = 20
+	BACKUP_KEEP_DAILY  = 7
+	BACKUP_KEEP_WEEKLY = 4
+
+	// Number of operations kept for undo, they survive restarts
+	UNDO_LIMIT = 100
+
+	// Days a snoozed contact stays off the overdue list
+	SNOOZE_DAYS = 7
+
+	// Days ahead the agenda looks and how far snoozing a reminder pushes it
+	AGENDA_DAYS          = 7
+	REMINDER_SNOOZE_DAYS = 1
+
+	// Weakest relation that counts as knowing someone well enough to introduce them
+	INTRO_MIN_STRENGTH = 4
 )
@@ -0,0 +1,196 @@
+package crypt
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/N3moAhead/connect3/internal/fileutil"
+	"golang.org/x/crypto/argon2"
+)
+
+const FORMAT = "argon2id+aes-256-gcm"
+
+var (
+	ErrWrongPassphrase  = errors.New("wrong passphrase or damaged file")
+	ErrPassphraseNeeded = errors.New("the database is encrypted and no passphrase was given")
+)
+
+// envelope is what an encrypted database looks like on disk.
+// The format key comes first so IsEncrypted can check the start of the file.
+type envelope struct {
+	Format     string `json:"c3_encrypted"`
+	Salt       []byte `json:"salt"`
+	Time       uint32 `json:"time"`
+	Memory     uint32 `json:"memory"` // KiB
+	Threads    uint8  `json:"threads"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Argon2id parameters for new files, the RFC 9106 second recommendation
+const (
+	kdfTime    = 3
+	kdfMemory  = 64 * 1024
+	kdfThreads = 4
+)
+
+// Deriving a key takes a moment, so keys are cached for the session
+var (
+	keyMu sync.Mutex
+	keys  = map[string][]byte{}
+)
+
+func deriveKey(passphrase []byte, env envelope) []byte {
+	id := fmt.Sprintf("%x/%d/%d/%d/%x", env.Salt, env.Time, env.Memory, env.Threads, passphrase)
+	keyMu.Lock()
+	defer keyMu.Unlock()
+	if key, ok := keys[id]; ok {
+		return key
+	}
+	key := argon2.IDKey(passphrase, env.Salt, env.Time, env.Memory, env.Threads, 32)
+	keys[id] = key
+	return key
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// IsEncrypted reports if content is an encrypted database
+func IsEncrypted(content []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(content), []byte(`{"c3_encrypted"`))
+}
+
+// Encrypt seals plain with a key derived from passphrase
+func Encrypt(plain, passphrase []byte) ([]byte, error) {
+	env := envelope{Format: FORMAT, Time: kdfTime, Memory: kdfMemory, Threads: kdfThreads}
+	if s := sessionSalt(); s != nil {
+		env.Salt = s
+	} else {
+		env.Salt = make([]byte, 16)
+		if _, err := rand.Read(env.Salt); err != nil {
+			return nil, err
+		}
+	}
+	gcm, err := newGCM(deriveKey(passphrase, env))
+	if err != nil {
+		return nil, err
+	}
+	env.Nonce = make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(env.Nonce); err != nil {
+		return nil, err
+	}
+	env.Ciphertext = gcm.Seal(nil, env.Nonce, plain, []byte(env.Format))
+	return json.Marshal(env)
+}
+
+// Decrypt opens content written by Encrypt
+func Decrypt(content, passphrase []byte) ([]byte, error) {
+	var env envelope
+	if err := json.Unmarshal(content, &env); err != nil {
+		return nil, fmt.Errorf("reading encrypted file: %w", err)
+	}
+	if env.Format != FORMAT {
+		return nil, fmt.Errorf("unknown encryption format %q", env.Format)
+	}
+	gcm, err := newGCM(deriveKey(passphrase, env))
+	if err != nil {
+		return nil, err
+	}
+	if len(env.Nonce) != gcm.NonceSize() {
+		return nil, ErrWrongPassphrase
+	}
+	plain, err := gcm.Open(nil, env.Nonce, env.Ciphertext, []byte(env.Format))
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	rememberSalt(env.Salt)
+	return plain, nil
+}
+
+// --- Session ---
+
+// The passphrase of the open database. It is set once in main before
+// anything reads the database and from then on every database file
+// is read and written encrypted.
+var (
+	sessionMu   sync.Mutex
+	passphrase  []byte
+	currentSalt []byte
+)
+
+func SetPassphrase(p []byte) {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	passphrase = p
+}
+
+// Enabled reports if the session works on an encrypted database
+func Enabled() bool {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	return passphrase != nil
+}
+
+func sessionPassphrase() []byte {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	return passphrase
+}
+
+// Reusing the salt of the file we read keeps saves from deriving a new key each time
+func sessionSalt() []byte {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	return currentSalt
+}
+
+func rememberSalt(salt []byte) {
+	sessionMu.Lock()
+	defer sessionMu.Unlock()
+	currentSalt = salt
+}
+
+// ReadFile reads a database file and decrypts it if needed
+func ReadFile(path string) ([]byte, error) {
+	content, err := os.ReadFile(path)
+	if err != nil || !IsEncrypted(content) {
+		return content, err
+	}
+	plain, err := Open(content)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return plain, nil
+}
+
+// Open decrypts content with the session passphrase
+func Open(content []byte) ([]byte, error) {
+	p := sessionPassphrase()
+	if p == nil {
+		return nil, ErrPassphraseNeeded
+	}
+	return Decrypt(content, p)
+}
+
+// WriteFile writes a database file atomically, encrypted if the session is
+func WriteFile(path string, data []byte, perm os.FileMode) error {
+	if p := sessionPassphrase(); p != nil {
+		var err error
+		if data, err = Encrypt(data, p); err != nil {
+			return err
+		}
+	}
+	return fileutil.WriteAtomic(path, data, perm)
+}
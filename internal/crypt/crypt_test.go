@@ -0,0 +1,119 @@
+package crypt
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var plain = []byte(`{"version": "1.5.0", "people": [{"id": "p1", "name": "Ada"}]}`)
+
+// resetSession forgets the passphrase and salt once the test is done
+func resetSession(t *testing.T) {
+	t.Cleanup(func() {
+		SetPassphrase(nil)
+		rememberSalt(nil)
+	})
+}
+
+func TestRoundTrip(t *testing.T) {
+	resetSession(t)
+	sealed, err := Encrypt(plain, []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(sealed) || IsEncrypted(plain) {
+		t.Fatal("IsEncrypted does not tell the two apart")
+	}
+	if bytes.Contains(sealed, []byte("Ada")) {
+		t.Fatal("the plain text is readable in the encrypted file")
+	}
+	got, err := Decrypt(sealed, []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Fatalf("got %s, want %s", got, plain)
+	}
+}
+
+func TestWrongPassphrase(t *testing.T) {
+	resetSession(t)
+	sealed, err := Encrypt(plain, []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(sealed, []byte("battery staple")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("got %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestTamperedFile(t *testing.T) {
+	resetSession(t)
+	sealed, err := Encrypt(plain, []byte("correct horse"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var env envelope
+	if err := json.Unmarshal(sealed, &env); err != nil {
+		t.Fatal(err)
+	}
+	env.Ciphertext[0] ^= 1
+	tampered, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Decrypt(tampered, []byte("correct horse")); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("got %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestSessionFiles(t *testing.T) {
+	resetSession(t)
+	path := filepath.Join(t.TempDir(), "data.json")
+	SetPassphrase([]byte("correct horse"))
+	if err := WriteFile(path, plain, 0644); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !IsEncrypted(raw) {
+		t.Fatal("the file was written unencrypted")
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Fatalf("got %s, want %s", got, plain)
+	}
+
+	SetPassphrase(nil)
+	if _, err := ReadFile(path); !errors.Is(err, ErrPassphraseNeeded) {
+		t.Fatalf("got %v, want ErrPassphraseNeeded", err)
+	}
+	SetPassphrase([]byte("battery staple"))
+	if _, err := ReadFile(path); !errors.Is(err, ErrWrongPassphrase) {
+		t.Fatalf("got %v, want ErrWrongPassphrase", err)
+	}
+}
+
+func TestPlainFilesPassThrough(t *testing.T) {
+	resetSession(t)
+	path := filepath.Join(t.TempDir(), "data.json")
+	if err := WriteFile(path, plain, 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, plain) {
+		t.Fatalf("got %s, want %s", got, plain)
+	}
+}
@@ -1,12 +1,19 @@
 package db
 
 import (
+	"github.com/N3moAhead/connect3/internal/interaction"
 	"github.com/N3moAhead/connect3/internal/person"
 	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/reminder"
 )
 
 type Database struct {
-	People    []person.Person     `json:"people"`
-	Relations []relation.Relation `json:"relations"`
-	Version   string              `json:"version"`
+	People       []person.Person           `json:"people"`
+	Relations    []relation.Relation       `json:"relations"`
+	Fields       []FieldDef                `json:"fields"`
+	Interactions []interaction.Interaction `json:"interactions"`
+	Reminders    []reminder.Reminder       `json:"reminders"`
+	Version      string                    `json:"version"`
+	Undo         *UndoLog                  `json:"undo,omitempty"`
+	Events       []Event                   `json:"events,omitempty"`
 }
@@ -0,0 +1,45 @@
+package db
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SyntaxError points to the place in a database file that could not be parsed
+type SyntaxError struct {
+	Path   string
+	Offset int64 // Byte offset into the file
+	Line   int
+	Column int
+	Err    error
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("%s is not valid json at line %d, column %d (byte %d): %v", e.Path, e.Line, e.Column, e.Offset, e.Err)
+}
+
+func (e *SyntaxError) Unwrap() error { return e.Err }
+
+// DescribeJSONError adds the line and column to errors returned by json.Unmarshal.
+// Errors without a position are returned as they are.
+func DescribeJSONError(path string, content []byte, err error) error {
+	var offset int64
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.As(err, &syntaxErr):
+		offset = syntaxErr.Offset
+	case errors.As(err, &typeErr):
+		offset = typeErr.Offset
+	default:
+		return fmt.Errorf("%s: %w", path, err)
+	}
+
+	offset = min(offset, int64(len(content)))
+	before := content[:offset]
+	line := bytes.Count(before, []byte("\n")) + 1
+	column := int(offset) - bytes.LastIndexByte(before, '\n')
+	return &SyntaxError{Path: path, Offset: offset, Line: line, Column: column, Err: err}
+}
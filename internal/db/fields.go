@@ -0,0 +1,108 @@
+package db
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/config"
+)
+
+// Types of custom fields
+const (
+	FieldText   = "text"
+	FieldNumber = "number"
+	FieldDate   = "date"
+	FieldBool   = "bool"
+	FieldEnum   = "enum"
+)
+
+var FieldTypes = []string{FieldText, FieldNumber, FieldDate, FieldBool, FieldEnum}
+
+// FieldDef defines a custom field every person can have a value for.
+// Values live in person.Person.Custom under the name of the field.
+type FieldDef struct {
+	Name    string   `json:"name"`
+	Type    string   `json:"type"`
+	Options []string `json:"options,omitempty"` // Allowed values of an enum
+}
+
+// Check reports what is wrong with the definition itself
+func (f FieldDef) Check() error {
+	if strings.TrimSpace(f.Name) == "" {
+		return fmt.Errorf("a field needs a name")
+	}
+	if !slices.Contains(FieldTypes, f.Type) {
+		return fmt.Errorf("field %s: unknown type %q, use one of %s", f.Name, f.Type, strings.Join(FieldTypes, ", "))
+	}
+	if f.Type == FieldEnum && len(f.Options) == 0 {
+		return fmt.Errorf("field %s: an enum needs options", f.Name)
+	}
+	return nil
+}
+
+// Normalize checks value against the type of the field and
+// returns it the way it is stored, e.g. "yes" becomes "true"
+func (f FieldDef) Normalize(value string) (string, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return "", nil
+	}
+	switch f.Type {
+	case FieldNumber:
+		if _, err := strconv.ParseFloat(value, 64); err != nil {
+			return "", fmt.Errorf("%s: %q is not a number", f.Name, value)
+		}
+	case FieldDate:
+		if _, err := time.Parse(config.DATE_LAYOUT, value); err != nil {
+			return "", fmt.Errorf("%s: %q is not a date like 2024-12-31", f.Name, value)
+		}
+	case FieldBool:
+		switch strings.ToLower(value) {
+		case "true", "yes", "y", "1":
+			return "true", nil
+		case "false", "no", "n", "0":
+			return "false", nil
+		}
+		return "", fmt.Errorf("%s: %q is not yes or no", f.Name, value)
+	case FieldEnum:
+		for _, o := range f.Options {
+			if strings.EqualFold(o, value) {
+				return o, nil
+			}
+		}
+		return "", fmt.Errorf("%s: %q is not one of %s", f.Name, value, strings.Join(f.Options, ", "))
+	}
+	return value, nil
+}
+
+// Compare orders two normalized values of the field, numbers
+// by value and everything else as text. Dates sort as text anyways.
+func (f FieldDef) Compare(a, b string) int {
+	if f.Type == FieldNumber {
+		x, errA := strconv.ParseFloat(a, 64)
+		y, errB := strconv.ParseFloat(b, 64)
+		if errA == nil && errB == nil {
+			switch {
+			case x < y:
+				return -1
+			case x > y:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(strings.ToLower(a), strings.ToLower(b))
+}
+
+// FindField returns the definition called name
+func FindField(defs []FieldDef, name string) (FieldDef, bool) {
+	for _, f := range defs {
+		if strings.EqualFold(f.Name, name) {
+			return f, true
+		}
+	}
+	return FieldDef{}, false
+}
@@ -0,0 +1,38 @@
+package db
+
+import "time"
+
+// Event actions
+const (
+	ActionCreate = "create"
+	ActionUpdate = "update"
+	ActionDelete = "delete"
+)
+
+// Event is one entry of the append-only change history.
+// Updates get one event per changed field.
+type Event struct {
+	Time     time.Time `json:"time"`
+	Entity   string    `json:"entity"`
+	EntityID string    `json:"entity_id"`
+	Label    string    `json:"label"`             // Name of the entity at that time, e.g. "Alice - Bob"
+	Related  []string  `json:"related,omitempty"` // IDs of the people a relation event belongs to
+	Action   string    `json:"action"`
+	Field    string    `json:"field,omitempty"`
+	Old      string    `json:"old,omitempty"`
+	New      string    `json:"new,omitempty"`
+}
+
+// Concerns reports if the event is about the person with the given id
+// or one of their relations
+func (e Event) Concerns(personID string) bool {
+	if e.EntityID == personID {
+		return true
+	}
+	for _, id := range e.Related {
+		if id == personID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package db
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Entity kinds a Change can be about
+const (
+	EntityPerson      = "person"
+	EntityRelation    = "relation"
+	EntityField       = "field"
+	EntityInteraction = "interaction"
+	EntityReminder    = "reminder"
+)
+
+// Change is one entity before and after a write.
+// Before is empty for creates, After is empty for deletes.
+type Change struct {
+	Entity string          `json:"entity"`
+	ID     string          `json:"id"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Operation is one user action, e.g. deleting a person together with their relations
+type Operation struct {
+	Name    string    `json:"name"`
+	Time    time.Time `json:"time"`
+	Changes []Change  `json:"changes"`
+}
+
+// UndoLog holds the operations that can be undone and redone, oldest first
+type UndoLog struct {
+	Undo []Operation `json:"undo"`
+	Redo []Operation `json:"redo"`
+}
@@ -0,0 +1,284 @@
+package db
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/reminder"
+	"github.com/google/uuid"
+)
+
+const (
+	MIN_STRENGTH = 1
+	MAX_STRENGTH = 5
+)
+
+// Kinds of problems Validate reports
+const (
+	ProblemDuplicateID      = "duplicate id"
+	ProblemDanglingRelation = "dangling relation"
+	ProblemSelfRelation     = "self relation"
+	ProblemStrength         = "strength out of range"
+	ProblemField            = "invalid field"
+	ProblemFieldValue       = "invalid field value"
+	ProblemParticipant      = "missing participant"
+	ProblemInteraction      = "invalid interaction"
+	ProblemCadence          = "invalid cadence"
+	ProblemDanglingReminder = "dangling reminder"
+	ProblemReminder         = "invalid reminder"
+)
+
+// Problem is something in the database that should not be there
+type Problem struct {
+	Kind    string
+	Entity  string // EntityPerson, EntityRelation, EntityField, EntityInteraction or EntityReminder
+	ID      string
+	Message string
+}
+
+func (p Problem) String() string {
+	return fmt.Sprintf("%s %s: %s", p.Entity, p.ID, p.Message)
+}
+
+// Validate checks that IDs are unique, that relations point to people
+// that exist and not to themselves, that strengths are in range,
+// that custom fields are well defined and hold values of their type,
+// that cadences make sense and that interactions have valid dates
+// and participants who exist
+func Validate(database Database) []Problem {
+	problems := []Problem{}
+	fields := map[string]FieldDef{}
+	for _, f := range database.Fields {
+		if err := f.Check(); err != nil {
+			problems = append(problems, Problem{Kind: ProblemField, Entity: EntityField, ID: f.Name, Message: err.Error()})
+			continue
+		}
+		key := strings.ToLower(f.Name)
+		if _, ok := fields[key]; ok {
+			problems = append(problems, Problem{Kind: ProblemField, Entity: EntityField, ID: f.Name,
+				Message: "name is used by more than one field"})
+		}
+		fields[key] = f
+	}
+
+	people := map[string]bool{}
+	for _, p := range database.People {
+		if people[p.ID] {
+			problems = append(problems, Problem{Kind: ProblemDuplicateID, Entity: EntityPerson, ID: p.ID,
+				Message: fmt.Sprintf("%q uses an id that is already taken", p.Name)})
+		}
+		people[p.ID] = true
+		if err := checkCadence(p); err != nil {
+			problems = append(problems, Problem{Kind: ProblemCadence, Entity: EntityPerson, ID: p.ID,
+				Message: fmt.Sprintf("%q %s", p.Name, err)})
+		}
+		// Values of fields that were deleted are kept, they come back with the field
+		for _, name := range slices.Sorted(maps.Keys(p.Custom)) {
+			value := p.Custom[name]
+			f, ok := fields[strings.ToLower(name)]
+			if !ok {
+				continue
+			}
+			if normalized, err := f.Normalize(value); err != nil || normalized != value {
+				problems = append(problems, Problem{Kind: ProblemFieldValue, Entity: EntityPerson, ID: p.ID,
+					Message: fmt.Sprintf("%q has the value %q for %s, which does not fit a %s field", p.Name, value, f.Name, f.Type)})
+			}
+		}
+	}
+
+	relations := map[string]bool{}
+	for _, r := range database.Relations {
+		if relations[r.ID] {
+			problems = append(problems, Problem{Kind: ProblemDuplicateID, Entity: EntityRelation, ID: r.ID,
+				Message: "id is used by more than one relation"})
+		}
+		relations[r.ID] = true
+		for _, end := range []string{r.FromID, r.ToID} {
+			if !people[end] {
+				problems = append(problems, Problem{Kind: ProblemDanglingRelation, Entity: EntityRelation, ID: r.ID,
+					Message: fmt.Sprintf("points to person %q who does not exist", end)})
+			}
+		}
+		if r.FromID == r.ToID {
+			problems = append(problems, Problem{Kind: ProblemSelfRelation, Entity: EntityRelation, ID: r.ID,
+				Message: "connects a person with themselves"})
+		}
+		if r.Strength < MIN_STRENGTH || r.Strength > MAX_STRENGTH {
+			problems = append(problems, Problem{Kind: ProblemStrength, Entity: EntityRelation, ID: r.ID,
+				Message: fmt.Sprintf("strength %d is not between %d and %d", r.Strength, MIN_STRENGTH, MAX_STRENGTH)})
+		}
+	}
+
+	interactions := map[string]bool{}
+	for _, in := range database.Interactions {
+		if interactions[in.ID] {
+			problems = append(problems, Problem{Kind: ProblemDuplicateID, Entity: EntityInteraction, ID: in.ID,
+				Message: "id is used by more than one interaction"})
+		}
+		interactions[in.ID] = true
+		if len(in.Participants) == 0 {
+			problems = append(problems, Problem{Kind: ProblemParticipant, Entity: EntityInteraction, ID: in.ID,
+				Message: "nobody took part in it"})
+		}
+		for _, id := range in.Participants {
+			if !people[id] {
+				problems = append(problems, Problem{Kind: ProblemParticipant, Entity: EntityInteraction, ID: in.ID,
+					Message: fmt.Sprintf("has the participant %q who does not exist", id)})
+			}
+		}
+		if err := in.Check(); err != nil {
+			problems = append(problems, Problem{Kind: ProblemInteraction, Entity: EntityInteraction, ID: in.ID, Message: err.Error()})
+		}
+	}
+
+	reminders := map[string]bool{}
+	for _, r := range database.Reminders {
+		if reminders[r.ID] {
+			problems = append(problems, Problem{Kind: ProblemDuplicateID, Entity: EntityReminder, ID: r.ID,
+				Message: "id is used by more than one reminder"})
+		}
+		reminders[r.ID] = true
+		if !people[r.PersonID] {
+			problems = append(problems, Problem{Kind: ProblemDanglingReminder, Entity: EntityReminder, ID: r.ID,
+				Message: fmt.Sprintf("is for %q who does not exist", r.PersonID)})
+		}
+		if err := r.Check(); err != nil {
+			problems = append(problems, Problem{Kind: ProblemReminder, Entity: EntityReminder, ID: r.ID, Message: err.Error()})
+		}
+	}
+	return problems
+}
+
+// Fix repairs what can be repaired without guessing: dangling relations
+// are dropped, strengths are clamped, duplicate IDs get a new one
+// and broken cadences and snoozes, of people and reminders, are reset.
+// Participants who do not exist are taken out of their interactions,
+// interactions nobody is left in are dropped, and so are reminders
+// for people who do not exist.
+// Relations keep pointing to the first person with a duplicated ID.
+// Self relations are left alone, only the user knows what they meant.
+func Fix(database Database) (Database, []Problem) {
+	fixed := []Problem{}
+
+	people := map[string]bool{}
+	newPeople := append(database.People[:0:0], database.People...)
+	for i, p := range newPeople {
+		if people[p.ID] {
+			newPeople[i].ID = uuid.New().String()
+			fixed = append(fixed, Problem{Kind: ProblemDuplicateID, Entity: EntityPerson, ID: p.ID,
+				Message: fmt.Sprintf("%q got the new id %s", p.Name, newPeople[i].ID)})
+		}
+		people[newPeople[i].ID] = true
+		if err := checkCadence(p); err != nil {
+			newPeople[i].Cadence = max(p.Cadence, 0)
+			newPeople[i].SnoozedUntil = ""
+			fixed = append(fixed, Problem{Kind: ProblemCadence, Entity: EntityPerson, ID: newPeople[i].ID,
+				Message: fmt.Sprintf("%q %s, it was reset", p.Name, err)})
+		}
+	}
+	database.People = newPeople
+
+	relations := map[string]bool{}
+	newRels := []relation.Relation{}
+	for _, r := range database.Relations {
+		if !people[r.FromID] || !people[r.ToID] {
+			fixed = append(fixed, Problem{Kind: ProblemDanglingRelation, Entity: EntityRelation, ID: r.ID,
+				Message: "removed, it pointed to a person who does not exist"})
+			continue
+		}
+		if relations[r.ID] {
+			old := r.ID
+			r.ID = uuid.New().String()
+			fixed = append(fixed, Problem{Kind: ProblemDuplicateID, Entity: EntityRelation, ID: old,
+				Message: fmt.Sprintf("got the new id %s", r.ID)})
+		}
+		relations[r.ID] = true
+		if r.Strength < MIN_STRENGTH || r.Strength > MAX_STRENGTH {
+			old := r.Strength
+			r.Strength = min(max(r.Strength, MIN_STRENGTH), MAX_STRENGTH)
+			fixed = append(fixed, Problem{Kind: ProblemStrength, Entity: EntityRelation, ID: r.ID,
+				Message: fmt.Sprintf("strength %d set to %d", old, r.Strength)})
+		}
+		newRels = append(newRels, r)
+	}
+	database.Relations = newRels
+
+	interactions := map[string]bool{}
+	newInteractions := []interaction.Interaction{}
+	for _, in := range database.Interactions {
+		participants := []string{}
+		for _, id := range in.Participants {
+			if people[id] {
+				participants = append(participants, id)
+			} else {
+				fixed = append(fixed, Problem{Kind: ProblemParticipant, Entity: EntityInteraction, ID: in.ID,
+					Message: fmt.Sprintf("removed the participant %q who does not exist", id)})
+			}
+		}
+		if len(participants) == 0 {
+			fixed = append(fixed, Problem{Kind: ProblemParticipant, Entity: EntityInteraction, ID: in.ID,
+				Message: "removed, nobody who took part in it exists"})
+			continue
+		}
+		in.Participants = participants
+		if interactions[in.ID] {
+			old := in.ID
+			in.ID = uuid.New().String()
+			fixed = append(fixed, Problem{Kind: ProblemDuplicateID, Entity: EntityInteraction, ID: old,
+				Message: fmt.Sprintf("got the new id %s", in.ID)})
+		}
+		interactions[in.ID] = true
+		newInteractions = append(newInteractions, in)
+	}
+	database.Interactions = newInteractions
+
+	reminders := map[string]bool{}
+	newReminders := []reminder.Reminder{}
+	for _, r := range database.Reminders {
+		if !people[r.PersonID] {
+			fixed = append(fixed, Problem{Kind: ProblemDanglingReminder, Entity: EntityReminder, ID: r.ID,
+				Message: "removed, it was for a person who does not exist"})
+			continue
+		}
+		if reminders[r.ID] {
+			old := r.ID
+			r.ID = uuid.New().String()
+			fixed = append(fixed, Problem{Kind: ProblemDuplicateID, Entity: EntityReminder, ID: old,
+				Message: fmt.Sprintf("got the new id %s", r.ID)})
+		}
+		reminders[r.ID] = true
+		if r.SnoozedUntil != "" {
+			if _, err := interaction.ParseDate(r.SnoozedUntil); err != nil {
+				fixed = append(fixed, Problem{Kind: ProblemReminder, Entity: EntityReminder, ID: r.ID,
+					Message: fmt.Sprintf("snoozed until %q, which is not a date, it was reset", r.SnoozedUntil)})
+				r.SnoozedUntil = ""
+			}
+		}
+		newReminders = append(newReminders, r)
+	}
+	database.Reminders = newReminders
+
+	if len(fixed) > 0 {
+		// The undo log may refer to what was just removed or renamed
+		database.Undo = nil
+	}
+	return database, fixed
+}
+
+// checkCadence reports a negative cadence or a snooze that is not a date
+func checkCadence(p person.Person) error {
+	if p.Cadence < 0 {
+		return fmt.Errorf("has the cadence %d, it can not be negative", p.Cadence)
+	}
+	if p.SnoozedUntil != "" {
+		if _, err := interaction.ParseDate(p.SnoozedUntil); err != nil {
+			return fmt.Errorf("is snoozed until %q, which is not a date", p.SnoozedUntil)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+package db
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/reminder"
+)
+
+// valid has one of everything and no problems
+func valid() Database {
+	return Database{
+		People: []person.Person{
+			{ID: "p1", Name: "Ada", Custom: map[string]string{"age": "36"}, Cadence: 30, SnoozedUntil: "2024-07-01"},
+			{ID: "p2", Name: "Grace"},
+		},
+		Relations: []relation.Relation{{ID: "r1", FromID: "p1", ToID: "p2", Strength: 3}},
+		Fields:    []FieldDef{{Name: "Age", Type: FieldNumber}},
+		Interactions: []interaction.Interaction{
+			{ID: "i1", Date: "2024-05-01", Kind: interaction.KindCall, Participants: []string{"p1", "p2"}},
+		},
+		Reminders: []reminder.Reminder{{ID: "m1", PersonID: "p1", Date: "2024-12-10"}},
+		Undo:      &UndoLog{Undo: []Operation{{Name: "Create Ada"}}},
+	}
+}
+
+func kinds(problems []Problem) []string {
+	list := []string{}
+	for _, p := range problems {
+		list = append(list, p.Kind)
+	}
+	return list
+}
+
+func TestValidAsIs(t *testing.T) {
+	if problems := Validate(valid()); len(problems) != 0 {
+		t.Fatalf("got %v", problems)
+	}
+	if fixed, problems := Fix(valid()); len(problems) != 0 || fixed.Undo == nil {
+		t.Fatalf("Fix changed a valid database: %v", problems)
+	}
+}
+
+func TestValidateAndFix(t *testing.T) {
+	tests := []struct {
+		name  string
+		spoil func(d *Database)
+		found []string // What Validate reports
+		fixed []string // What Fix repairs
+		left  []string // What Validate still reports after Fix, only the user can repair it
+		check func(t *testing.T, d Database)
+	}{
+		{
+			name:  "duplicate person",
+			spoil: func(d *Database) { d.People = append(d.People, person.Person{ID: "p1", Name: "Ada 2"}) },
+			found: []string{ProblemDuplicateID},
+			fixed: []string{ProblemDuplicateID},
+			check: func(t *testing.T, d Database) {
+				// The relation keeps pointing to the first one
+				if d.People[2].ID == "p1" || d.People[0].ID != "p1" || d.Relations[0].FromID != "p1" {
+					t.Fatalf("got %v", d.People)
+				}
+			},
+		},
+		{
+			name: "duplicate relation, interaction and reminder",
+			spoil: func(d *Database) {
+				d.Relations = append(d.Relations, d.Relations[0])
+				d.Interactions = append(d.Interactions, d.Interactions[0])
+				d.Reminders = append(d.Reminders, d.Reminders[0])
+			},
+			found: []string{ProblemDuplicateID, ProblemDuplicateID, ProblemDuplicateID},
+			fixed: []string{ProblemDuplicateID, ProblemDuplicateID, ProblemDuplicateID},
+			check: func(t *testing.T, d Database) {
+				if d.Relations[1].ID == "r1" || d.Interactions[1].ID == "i1" || d.Reminders[1].ID == "m1" {
+					t.Fatal("the copies kept their ids")
+				}
+			},
+		},
+		{
+			name:  "dangling relation",
+			spoil: func(d *Database) { d.Relations[0].ToID = "nobody" },
+			found: []string{ProblemDanglingRelation},
+			fixed: []string{ProblemDanglingRelation},
+			check: func(t *testing.T, d Database) {
+				if len(d.Relations) != 0 {
+					t.Fatalf("got %v, want it removed", d.Relations)
+				}
+			},
+		},
+		{
+			name:  "self relation",
+			spoil: func(d *Database) { d.Relations[0].ToID = "p1" },
+			found: []string{ProblemSelfRelation},
+			left:  []string{ProblemSelfRelation},
+		},
+		{
+			name:  "strength",
+			spoil: func(d *Database) { d.Relations[0].Strength = 9 },
+			found: []string{ProblemStrength},
+			fixed: []string{ProblemStrength},
+			check: func(t *testing.T, d Database) {
+				if d.Relations[0].Strength != MAX_STRENGTH {
+					t.Fatalf("got strength %d", d.Relations[0].Strength)
+				}
+			},
+		},
+		{
+			name: "field",
+			spoil: func(d *Database) {
+				d.Fields = append(d.Fields, FieldDef{Name: "Mood", Type: "feeling"}, FieldDef{Name: "age", Type: FieldText})
+			},
+			found: []string{ProblemField, ProblemField},
+			left:  []string{ProblemField, ProblemField},
+		},
+		{
+			name:  "field value",
+			spoil: func(d *Database) { d.People[0].Custom["age"] = "old" },
+			found: []string{ProblemFieldValue},
+			left:  []string{ProblemFieldValue},
+		},
+		{
+			name:  "participant who does not exist",
+			spoil: func(d *Database) { d.Interactions[0].Participants = append(d.Interactions[0].Participants, "nobody") },
+			found: []string{ProblemParticipant},
+			fixed: []string{ProblemParticipant},
+			check: func(t *testing.T, d Database) {
+				if !slices.Equal(d.Interactions[0].Participants, []string{"p1", "p2"}) {
+					t.Fatalf("got %v", d.Interactions[0].Participants)
+				}
+			},
+		},
+		{
+			name:  "nobody took part",
+			spoil: func(d *Database) { d.Interactions[0].Participants = []string{"nobody"} },
+			found: []string{ProblemParticipant},
+			fixed: []string{ProblemParticipant, ProblemParticipant},
+			check: func(t *testing.T, d Database) {
+				if len(d.Interactions) != 0 {
+					t.Fatalf("got %v, want it removed", d.Interactions)
+				}
+			},
+		},
+		{
+			name:  "interaction",
+			spoil: func(d *Database) { d.Interactions[0].Kind = "letter" },
+			found: []string{ProblemInteraction},
+			left:  []string{ProblemInteraction},
+		},
+		{
+			name: "cadence",
+			spoil: func(d *Database) {
+				d.People[0].Cadence = -3
+				d.People[1].SnoozedUntil = "soon"
+			},
+			found: []string{ProblemCadence, ProblemCadence},
+			fixed: []string{ProblemCadence, ProblemCadence},
+			check: func(t *testing.T, d Database) {
+				if d.People[0].Cadence != 0 || d.People[0].SnoozedUntil != "" || d.People[1].SnoozedUntil != "" {
+					t.Fatalf("got %+v", d.People)
+				}
+			},
+		},
+		{
+			name:  "dangling reminder",
+			spoil: func(d *Database) { d.Reminders[0].PersonID = "nobody" },
+			found: []string{ProblemDanglingReminder},
+			fixed: []string{ProblemDanglingReminder},
+			check: func(t *testing.T, d Database) {
+				if len(d.Reminders) != 0 {
+					t.Fatalf("got %v, want it removed", d.Reminders)
+				}
+			},
+		},
+		{
+			name:  "reminder",
+			spoil: func(d *Database) { d.Reminders[0].Repeat = "weekly" },
+			found: []string{ProblemReminder},
+			left:  []string{ProblemReminder},
+		},
+		{
+			name:  "reminder snooze",
+			spoil: func(d *Database) { d.Reminders[0].SnoozedUntil = "tomorrow" },
+			found: []string{ProblemReminder},
+			fixed: []string{ProblemReminder},
+			check: func(t *testing.T, d Database) {
+				if d.Reminders[0].SnoozedUntil != "" || d.Reminders[0].Date != "2024-12-10" {
+					t.Fatalf("got %+v", d.Reminders[0])
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			database := valid()
+			tt.spoil(&database)
+			if got := kinds(Validate(database)); !slices.Equal(got, tt.found) {
+				t.Fatalf("Validate: got %v, want %v", got, tt.found)
+			}
+
+			fixed, problems := Fix(database)
+			want := tt.fixed
+			if want == nil {
+				want = []string{}
+			}
+			if got := kinds(problems); !slices.Equal(got, want) {
+				t.Fatalf("Fix: got %v, want %v", got, want)
+			}
+			// The undo log may point to what Fix removed or renamed
+			if (fixed.Undo == nil) != (len(problems) > 0) {
+				t.Fatalf("undo log kept: %v with %d repairs", fixed.Undo != nil, len(problems))
+			}
+			left := tt.left
+			if left == nil {
+				left = []string{}
+			}
+			if got := kinds(Validate(fixed)); !slices.Equal(got, left) {
+				t.Fatalf("after Fix: got %v, want %v", got, left)
+			}
+			if tt.check != nil {
+				tt.check(t, fixed)
+			}
+		})
+	}
+}
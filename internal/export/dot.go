@@ -0,0 +1,42 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteDOT writes n as a Graphviz digraph. Stronger relations get thicker lines,
+// the tags and custom fields become node attributes Graphviz passes through.
+// Custom fields are named with ATTRIBUTE_PREFIX and quoted, any name is safe.
+func WriteDOT(w io.Writer, n Network) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "digraph connect3 {")
+	for _, p := range n.People {
+		attrs := []string{
+			"label=" + dotQuote(p.Name),
+			"tags=" + dotQuote(strings.Join(p.Tags, TAG_SEPARATOR)),
+		}
+		for _, name := range n.Attributes {
+			if value, ok := customValue(p, name); ok {
+				attrs = append(attrs, dotQuote(ATTRIBUTE_PREFIX+name)+"="+dotQuote(value))
+			}
+		}
+		fmt.Fprintf(bw, "  %s [%s];\n", dotQuote(p.ID), strings.Join(attrs, ", "))
+	}
+	for _, r := range n.Relations {
+		fmt.Fprintf(bw, "  %s -> %s [id=%s, label=%s, weight=%d, penwidth=%d];\n",
+			dotQuote(r.FromID), dotQuote(r.ToID), dotQuote(r.ID), dotQuote(r.Description), r.Strength, max(r.Strength, 1))
+	}
+	fmt.Fprintln(bw, "}")
+	return bw.Flush()
+}
+
+// dotQuote turns s into a quoted DOT id
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	s = strings.ReplaceAll(s, "\n", `\n`)
+	return `"` + s + `"`
+}
@@ -0,0 +1,99 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+)
+
+// Formats Write knows
+const (
+	FormatDOT     = "dot"
+	FormatGraphML = "graphml"
+	FormatGEXF    = "gexf"
+)
+
+var Formats = []string{FormatDOT, FormatGraphML, FormatGEXF}
+
+// TAG_SEPARATOR joins the tags of a person into one attribute
+const TAG_SEPARATOR = ";"
+
+// ATTRIBUTE_PREFIX goes in front of every custom field, so a field called
+// e.g. label or color does not clash with what the formats use themselves
+const ATTRIBUTE_PREFIX = "c3_"
+
+// Network is what gets exported: people as nodes, relations as directed edges
+type Network struct {
+	People     []person.Person
+	Relations  []relation.Relation // Only between People
+	Attributes []string            // Names of the custom fields, defined ones first
+}
+
+// customValue returns the value p has for the field name. Like db.FindField
+// it ignores case, values saved before a field was renamed still count.
+func customValue(p person.Person, name string) (string, bool) {
+	if value, ok := p.Custom[name]; ok {
+		return value, true
+	}
+	for field, value := range p.Custom {
+		if strings.EqualFold(field, name) {
+			return value, true
+		}
+	}
+	return "", false
+}
+
+// sameField reports if name is one of the names, ignoring case
+func sameField(names []string, name string) bool {
+	return slices.ContainsFunc(names, func(n string) bool { return strings.EqualFold(n, name) })
+}
+
+// Select takes the people with tag, everyone if tag is empty, and the relations
+// between them. Custom values without a field definition are kept as attributes too.
+func Select(database db.Database, tag string) Network {
+	n := Network{People: []person.Person{}, Relations: []relation.Relation{}, Attributes: []string{}}
+	in := map[string]bool{}
+	for _, p := range database.People {
+		if tag == "" || slices.Contains(p.Tags, tag) {
+			n.People = append(n.People, p)
+			in[p.ID] = true
+		}
+	}
+	for _, r := range database.Relations {
+		if in[r.FromID] && in[r.ToID] {
+			n.Relations = append(n.Relations, r)
+		}
+	}
+	for _, f := range database.Fields {
+		n.Attributes = append(n.Attributes, f.Name)
+	}
+	undefined := []string{}
+	for _, p := range n.People {
+		for name := range p.Custom {
+			if !sameField(n.Attributes, name) && !sameField(undefined, name) {
+				undefined = append(undefined, name)
+			}
+		}
+	}
+	slices.Sort(undefined)
+	n.Attributes = append(n.Attributes, undefined...)
+	return n
+}
+
+// Write writes n in format to w
+func Write(w io.Writer, format string, n Network) error {
+	switch format {
+	case FormatDOT:
+		return WriteDOT(w, n)
+	case FormatGraphML:
+		return WriteGraphML(w, n)
+	case FormatGEXF:
+		return WriteGEXF(w, n)
+	}
+	return fmt.Errorf("unknown format %q, use one of %s", format, strings.Join(Formats, ", "))
+}
@@ -0,0 +1,69 @@
+package export
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+)
+
+var update = flag.Bool("update", false, "Rewrite the golden files in testdata")
+
+// golden has a field named like a DOT attribute, a value saved under another
+// case than its field, a field nobody defined and text that needs escaping
+var golden = db.Database{
+	People: []person.Person{
+		{ID: "p1", Name: `Ada "the first" <Lovelace>`, Tags: []string{"math", "work"},
+			Custom: map[string]string{"label": "pioneer", "company": "Analytical & Co"}},
+		{ID: "p2", Name: "Grace", Tags: []string{},
+			Custom: map[string]string{"Company": "Navy", "ship": "USS\nHopper"}},
+		{ID: "p3", Name: "Alan", Tags: []string{"work"}},
+	},
+	Relations: []relation.Relation{
+		{ID: "r1", FromID: "p1", ToID: "p2", Strength: 4, Description: "Pen pals"},
+		{ID: "r2", FromID: "p2", ToID: "p3", Strength: 0, Description: ""},
+	},
+	Fields: []db.FieldDef{{Name: "Company", Type: db.FieldText}, {Name: "label", Type: db.FieldText}},
+}
+
+func TestGolden(t *testing.T) {
+	n := Select(golden, "")
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := Write(&out, format, n); err != nil {
+				t.Fatal(err)
+			}
+			path := filepath.Join("testdata", "network."+format)
+			if *update {
+				if err := os.WriteFile(path, out.Bytes(), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			want, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(out.Bytes(), want) {
+				t.Fatalf("output differs from %s, run go test -update to accept it:\n%s", path, out.String())
+			}
+		})
+	}
+}
+
+func TestSelectIgnoresCase(t *testing.T) {
+	n := Select(golden, "work")
+	want := []string{"Company", "label"}
+	if !slices.Equal(n.Attributes, want) {
+		t.Fatalf("got attributes %v, want %v", n.Attributes, want)
+	}
+	if len(n.People) != 2 || len(n.Relations) != 0 {
+		t.Fatalf("got %d people and %d relations, want Ada and Alan without relations", len(n.People), len(n.Relations))
+	}
+}
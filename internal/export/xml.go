@@ -0,0 +1,94 @@
+package export
+
+import (
+	"bufio"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// WriteGraphML writes n as GraphML, e.g. for Gephi, yEd or networkx.
+// Every custom field becomes a node key named with ATTRIBUTE_PREFIX,
+// relations carry their strength as weight.
+func WriteGraphML(w io.Writer, n Network) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, xml.Header+`<graphml xmlns="http://graphml.graphdrawing.org/xmlns">`)
+	fmt.Fprintln(bw, `  <key id="name" for="node" attr.name="name" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <key id="tags" for="node" attr.name="tags" attr.type="string"/>`)
+	for i, name := range n.Attributes {
+		fmt.Fprintf(bw, "  <key id=\"custom%d\" for=\"node\" attr.name=%s attr.type=\"string\"/>\n", i, xmlAttr(ATTRIBUTE_PREFIX+name))
+	}
+	fmt.Fprintln(bw, `  <key id="weight" for="edge" attr.name="weight" attr.type="int"/>`)
+	fmt.Fprintln(bw, `  <key id="label" for="edge" attr.name="label" attr.type="string"/>`)
+	fmt.Fprintln(bw, `  <graph id="connect3" edgedefault="directed">`)
+	for _, p := range n.People {
+		fmt.Fprintf(bw, "    <node id=%s>\n", xmlAttr(p.ID))
+		fmt.Fprintf(bw, "      <data key=\"name\">%s</data>\n", xmlText(p.Name))
+		fmt.Fprintf(bw, "      <data key=\"tags\">%s</data>\n", xmlText(strings.Join(p.Tags, TAG_SEPARATOR)))
+		for i, name := range n.Attributes {
+			if value, ok := customValue(p, name); ok {
+				fmt.Fprintf(bw, "      <data key=\"custom%d\">%s</data>\n", i, xmlText(value))
+			}
+		}
+		fmt.Fprintln(bw, "    </node>")
+	}
+	for _, r := range n.Relations {
+		fmt.Fprintf(bw, "    <edge id=%s source=%s target=%s>\n", xmlAttr(r.ID), xmlAttr(r.FromID), xmlAttr(r.ToID))
+		fmt.Fprintf(bw, "      <data key=\"weight\">%d</data>\n", r.Strength)
+		fmt.Fprintf(bw, "      <data key=\"label\">%s</data>\n", xmlText(r.Description))
+		fmt.Fprintln(bw, "    </edge>")
+	}
+	fmt.Fprintln(bw, "  </graph>")
+	fmt.Fprintln(bw, "</graphml>")
+	return bw.Flush()
+}
+
+// WriteGEXF writes n as GEXF 1.3, the native format of Gephi
+func WriteGEXF(w io.Writer, n Network) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, xml.Header+`<gexf xmlns="http://gexf.net/1.3" version="1.3">`)
+	fmt.Fprintln(bw, `  <meta><creator>connect3</creator></meta>`)
+	fmt.Fprintln(bw, `  <graph mode="static" defaultedgetype="directed">`)
+	fmt.Fprintln(bw, `    <attributes class="node">`)
+	fmt.Fprintln(bw, `      <attribute id="tags" title="tags" type="string"/>`)
+	for i, name := range n.Attributes {
+		fmt.Fprintf(bw, "      <attribute id=\"custom%d\" title=%s type=\"string\"/>\n", i, xmlAttr(ATTRIBUTE_PREFIX+name))
+	}
+	fmt.Fprintln(bw, `    </attributes>`)
+	fmt.Fprintln(bw, `    <nodes>`)
+	for _, p := range n.People {
+		fmt.Fprintf(bw, "      <node id=%s label=%s>\n", xmlAttr(p.ID), xmlAttr(p.Name))
+		fmt.Fprintln(bw, "        <attvalues>")
+		fmt.Fprintf(bw, "          <attvalue for=\"tags\" value=%s/>\n", xmlAttr(strings.Join(p.Tags, TAG_SEPARATOR)))
+		for i, name := range n.Attributes {
+			if value, ok := customValue(p, name); ok {
+				fmt.Fprintf(bw, "          <attvalue for=\"custom%d\" value=%s/>\n", i, xmlAttr(value))
+			}
+		}
+		fmt.Fprintln(bw, "        </attvalues>")
+		fmt.Fprintln(bw, "      </node>")
+	}
+	fmt.Fprintln(bw, `    </nodes>`)
+	fmt.Fprintln(bw, `    <edges>`)
+	for _, r := range n.Relations {
+		fmt.Fprintf(bw, "      <edge id=%s source=%s target=%s weight=\"%d\" label=%s/>\n",
+			xmlAttr(r.ID), xmlAttr(r.FromID), xmlAttr(r.ToID), r.Strength, xmlAttr(r.Description))
+	}
+	fmt.Fprintln(bw, `    </edges>`)
+	fmt.Fprintln(bw, `  </graph>`)
+	fmt.Fprintln(bw, `</gexf>`)
+	return bw.Flush()
+}
+
+// xmlText escapes s for use between tags
+func xmlText(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// xmlAttr escapes and quotes s as an attribute value
+func xmlAttr(s string) string {
+	return `"` + xmlText(s) + `"`
+}
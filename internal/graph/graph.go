@@ -0,0 +1,65 @@
+package graph
+
+import (
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/relation"
+)
+
+// Edge is a relation seen from one of the two people it connects
+type Edge struct {
+	To       string // The other person
+	Relation relation.Relation
+}
+
+// Index is the adjacency list of the network. Relations are directed in the
+// database but connect both people, so every relation is an edge from both ends.
+type Index struct {
+	edges map[string][]Edge
+}
+
+// New indexes the relations, in their order. Self relations are left out.
+func New(relations []relation.Relation) *Index {
+	ix := &Index{edges: map[string][]Edge{}}
+	for _, r := range relations {
+		if r.FromID == r.ToID {
+			continue
+		}
+		ix.edges[r.FromID] = append(ix.edges[r.FromID], Edge{To: r.ToID, Relation: r})
+		ix.edges[r.ToID] = append(ix.edges[r.ToID], Edge{To: r.FromID, Relation: r})
+	}
+	return ix
+}
+
+// Edges returns the relations of id, there can be more than one to the same person
+func (ix *Index) Edges(id string) []Edge {
+	return ix.edges[id]
+}
+
+// Neighbors returns everyone id has a relation with, each of them once
+func (ix *Index) Neighbors(id string) []string {
+	seen := map[string]bool{}
+	ids := []string{}
+	for _, e := range ix.edges[id] {
+		if !seen[e.To] {
+			seen[e.To] = true
+			ids = append(ids, e.To)
+		}
+	}
+	return ids
+}
+
+// Strength is the strongest relation between a and b in either direction, 0 if there is none
+func (ix *Index) Strength(a, b string) int {
+	strength := 0
+	for _, e := range ix.edges[a] {
+		if e.To == b {
+			strength = max(strength, Clamp(e.Relation.Strength))
+		}
+	}
+	return strength
+}
+
+// Clamp keeps a strength from a broken database between db.MIN_STRENGTH and db.MAX_STRENGTH
+func Clamp(strength int) int {
+	return min(max(strength, db.MIN_STRENGTH), db.MAX_STRENGTH)
+}
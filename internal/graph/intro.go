@@ -0,0 +1,112 @@
+package graph
+
+import (
+	"slices"
+)
+
+// Common is someone who shares connections with a person
+type Common struct {
+	ID     string
+	Mutual []string // The connections they share
+}
+
+// Intro suggests introducing A and B, who both know the people in Via well
+// but have no relation with each other
+type Intro struct {
+	A, B     string
+	Via      []string // Best first
+	Strength int      // Strength of A and B to the best Via added up
+}
+
+// Mutual lists the people a and b both have a relation with
+func (ix *Index) Mutual(a, b string) []string {
+	theirs := map[string]bool{}
+	for _, id := range ix.Neighbors(b) {
+		theirs[id] = true
+	}
+	mutual := []string{}
+	for _, id := range ix.Neighbors(a) {
+		if theirs[id] && id != a && id != b {
+			mutual = append(mutual, id)
+		}
+	}
+	return mutual
+}
+
+// Commons lists everyone who shares at least one connection with id,
+// most shared connections first
+func (ix *Index) Commons(id string) []Common {
+	index := map[string]int{}
+	commons := []Common{}
+	for _, via := range ix.Neighbors(id) {
+		for _, other := range ix.Neighbors(via) {
+			if other == id {
+				continue
+			}
+			i, ok := index[other]
+			if !ok {
+				i = len(commons)
+				index[other] = i
+				commons = append(commons, Common{ID: other})
+			}
+			commons[i].Mutual = append(commons[i].Mutual, via)
+		}
+	}
+	slices.SortStableFunc(commons, func(a, b Common) int { return len(b.Mutual) - len(a.Mutual) })
+	return commons
+}
+
+// Intros suggests introductions between people who both have a relation
+// of at least minStrength with someone, strongest first. The order of
+// people decides between suggestions that are just as strong.
+func (ix *Index) Intros(people []string, minStrength int) []Intro {
+	type pair struct{ a, b string }
+	index := map[pair]int{}
+	intros := []Intro{}
+	for _, via := range people {
+		strong := []string{}
+		for _, id := range ix.Neighbors(via) {
+			if ix.Strength(via, id) >= minStrength {
+				strong = append(strong, id)
+			}
+		}
+		for i, a := range strong {
+			for _, b := range strong[i+1:] {
+				if ix.Strength(a, b) > 0 {
+					continue
+				}
+				key := pair{a, b}
+				if _, ok := index[pair{b, a}]; ok {
+					key = pair{b, a}
+				}
+				strength := ix.Strength(via, a) + ix.Strength(via, b)
+				n, ok := index[key]
+				if !ok {
+					n = len(intros)
+					index[key] = n
+					intros = append(intros, Intro{A: key.a, B: key.b})
+				}
+				in := &intros[n]
+				in.Via = append(in.Via, via)
+				if strength > in.Strength {
+					// The best one goes first
+					in.Strength = strength
+					copy(in.Via[1:], in.Via[:len(in.Via)-1])
+					in.Via[0] = via
+				}
+			}
+		}
+	}
+	slices.SortStableFunc(intros, func(a, b Intro) int {
+		if a.Strength != b.Strength {
+			return b.Strength - a.Strength
+		}
+		return len(b.Via) - len(a.Via)
+	})
+	return intros
+}
+
+// Involves reports if id is one of the two people of the introduction or one of the Via
+func (in Intro) Involves(id string) bool {
+	return in.A == id || in.B == id || slices.Contains(in.Via, id)
+}
@@ -0,0 +1,116 @@
+package graph
+
+import (
+	"container/heap"
+
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/relation"
+)
+
+// Path is a way from one person to another through their relations
+type Path struct {
+	People []string            // From first, to last
+	Hops   []relation.Relation // Hops[i] connects People[i] and People[i+1]
+	Cost   int                 // Sum of the costs of the hops, see Cost
+}
+
+// Cost is what a hop over a relation of the given strength costs in StrongestPath.
+// The strongest tie costs 1 and every step weaker one more, so two strong
+// introductions beat one weak acquaintance.
+func Cost(strength int) int {
+	return db.MAX_STRENGTH + 1 - Clamp(strength)
+}
+
+// ShortestPath finds a path with the fewest hops. Among equally short paths
+// the one through the relations listed first wins. ok is false if there is none.
+func (ix *Index) ShortestPath(from, to string) (p Path, ok bool) {
+	prev := map[string]Edge{from: {}}
+	queue := []string{from}
+	for len(queue) > 0 && !has(prev, to) {
+		id := queue[0]
+		queue = queue[1:]
+		for _, e := range ix.edges[id] {
+			if !has(prev, e.To) {
+				prev[e.To] = Edge{To: id, Relation: e.Relation}
+				queue = append(queue, e.To)
+			}
+		}
+	}
+	if !has(prev, to) {
+		return p, false
+	}
+	return walkBack(prev, from, to), true
+}
+
+// StrongestPath finds the path with the lowest total Cost, the easiest
+// chain of introductions. ok is false if there is none.
+func (ix *Index) StrongestPath(from, to string) (p Path, ok bool) {
+	dist := map[string]int{from: 0}
+	prev := map[string]Edge{from: {}}
+	done := map[string]bool{}
+	q := &costQueue{{id: from}}
+	for q.Len() > 0 {
+		item := heap.Pop(q).(costItem)
+		if done[item.id] {
+			continue
+		}
+		done[item.id] = true
+		if item.id == to {
+			break
+		}
+		for _, e := range ix.edges[item.id] {
+			d := item.cost + Cost(e.Relation.Strength)
+			if old, seen := dist[e.To]; !done[e.To] && (!seen || d < old) {
+				dist[e.To] = d
+				prev[e.To] = Edge{To: item.id, Relation: e.Relation}
+				heap.Push(q, costItem{id: e.To, cost: d})
+			}
+		}
+	}
+	if !done[to] {
+		return p, false
+	}
+	return walkBack(prev, from, to), true
+}
+
+func has(prev map[string]Edge, id string) bool {
+	_, ok := prev[id]
+	return ok
+}
+
+// walkBack follows the edges in prev, which point to where each person was reached from
+func walkBack(prev map[string]Edge, from, to string) Path {
+	p := Path{People: []string{to}}
+	for id := to; id != from; id = prev[id].To {
+		e := prev[id]
+		p.People = append(p.People, e.To)
+		p.Hops = append(p.Hops, e.Relation)
+		p.Cost += Cost(e.Relation.Strength)
+	}
+	for i, j := 0, len(p.People)-1; i < j; i, j = i+1, j-1 {
+		p.People[i], p.People[j] = p.People[j], p.People[i]
+	}
+	for i, j := 0, len(p.Hops)-1; i < j; i, j = i+1, j-1 {
+		p.Hops[i], p.Hops[j] = p.Hops[j], p.Hops[i]
+	}
+	return p
+}
+
+type costItem struct {
+	id   string
+	cost int
+}
+
+// costQueue is a min heap for StrongestPath
+type costQueue []costItem
+
+func (q costQueue) Len() int           { return len(q) }
+func (q costQueue) Less(i, j int) bool { return q[i].cost < q[j].cost }
+func (q costQueue) Swap(i, j int)      { q[i], q[j] = q[j], q[i] }
+func (q *costQueue) Push(x any)        { *q = append(*q, x.(costItem)) }
+func (q *costQueue) Pop() any {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
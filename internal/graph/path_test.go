@@ -0,0 +1,89 @@
+package graph
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/N3moAhead/connect3/internal/relation"
+)
+
+func rel(id, from, to string, strength int) relation.Relation {
+	return relation.Relation{ID: id, FromID: from, ToID: to, Strength: strength}
+}
+
+// a - b - c - d with a weak shortcut a - d and a strong detour a - e - d,
+// x - y are on their own
+var network = []relation.Relation{
+	rel("ab", "a", "b", 3),
+	rel("bc", "b", "c", 3),
+	rel("dc", "d", "c", 3), // Against the direction of the path
+	rel("ad", "a", "d", 1),
+	rel("ae", "a", "e", 5),
+	rel("ed", "e", "d", 5),
+	rel("xy", "x", "y", 5),
+}
+
+func TestShortestPath(t *testing.T) {
+	ix := New(network)
+	tests := []struct {
+		from, to string
+		want     []string // nil if there is no path
+	}{
+		{"a", "d", []string{"a", "d"}},
+		{"c", "e", []string{"c", "d", "e"}},
+		{"e", "c", []string{"e", "d", "c"}},
+		// b - c - d is just as short, the relation listed first wins
+		{"b", "d", []string{"b", "a", "d"}},
+		{"a", "a", []string{"a"}},
+		{"a", "x", nil},
+		{"a", "nobody", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.from+"_to_"+tt.to, func(t *testing.T) {
+			p, ok := ix.ShortestPath(tt.from, tt.to)
+			if ok != (tt.want != nil) {
+				t.Fatalf("ok is %v for %v", ok, p.People)
+			}
+			if !slices.Equal(p.People, tt.want) {
+				t.Fatalf("got %v, want %v", p.People, tt.want)
+			}
+			if ok && len(p.Hops) != len(p.People)-1 {
+				t.Fatalf("%d hops for %d people", len(p.Hops), len(p.People))
+			}
+		})
+	}
+}
+
+func TestShortestPathHops(t *testing.T) {
+	p, ok := New(network).ShortestPath("c", "e")
+	if !ok {
+		t.Fatal("no path")
+	}
+	ids := []string{}
+	for _, h := range p.Hops {
+		ids = append(ids, h.ID)
+	}
+	if !slices.Equal(ids, []string{"dc", "ed"}) {
+		t.Fatalf("got hops %v", ids)
+	}
+	if p.Cost != Cost(3)+Cost(5) {
+		t.Fatalf("got cost %d, want %d", p.Cost, Cost(3)+Cost(5))
+	}
+}
+
+func TestStrongestPathTakesTheDetour(t *testing.T) {
+	p, ok := New(network).StrongestPath("a", "d")
+	if !ok {
+		t.Fatal("no path")
+	}
+	// Two of the strongest ties cost less than one of the weakest
+	if want := []string{"a", "e", "d"}; !slices.Equal(p.People, want) {
+		t.Fatalf("got %v, want %v", p.People, want)
+	}
+	if p.Cost != 2*Cost(5) {
+		t.Fatalf("got cost %d, want %d", p.Cost, 2*Cost(5))
+	}
+	if _, ok := New(network).StrongestPath("a", "y"); ok {
+		t.Fatal("found a path between two parts of the network")
+	}
+}
@@ -0,0 +1,236 @@
+package graph
+
+import (
+	"maps"
+	"slices"
+)
+
+// Stats describes the structure of a network
+type Stats struct {
+	People         []string // Everyone in the network, in the order given to Analyze
+	Degree         map[string]int
+	WeightedDegree map[string]int     // Strengths of all relations added up
+	Betweenness    map[string]float64 // Shortest paths between two others that go through someone
+	Articulation   []string           // People who split their component when they are gone
+	Bridges        [][2]string        // Relations that split their component when they are gone
+	Components     [][]string         // Parts of the network with no relation between them, biggest first
+	Communities    [][]string         // Tightly knit groups, biggest first, see communities
+}
+
+// communityRounds caps label propagation, it usually settles after a few rounds
+const communityRounds = 100
+
+// Analyze computes the stats of people, relations to anyone else are left out
+func (ix *Index) Analyze(people []string) Stats {
+	in := map[string]bool{}
+	for _, id := range people {
+		in[id] = true
+	}
+	adj := map[string][]string{}
+	for _, id := range people {
+		adj[id] = []string{}
+		for _, other := range ix.Neighbors(id) {
+			if in[other] {
+				adj[id] = append(adj[id], other)
+			}
+		}
+	}
+
+	s := Stats{
+		People:         people,
+		Degree:         map[string]int{},
+		WeightedDegree: map[string]int{},
+	}
+	for _, id := range people {
+		s.Degree[id] = len(adj[id])
+		for _, other := range adj[id] {
+			s.WeightedDegree[id] += ix.Strength(id, other)
+		}
+	}
+	s.Betweenness = betweenness(people, adj)
+	s.Articulation, s.Bridges = cuts(people, adj)
+	s.Components = components(people, adj)
+	s.Communities = ix.communities(people, adj)
+	return s
+}
+
+// betweenness is Brandes' algorithm on the unweighted network. Every pair
+// of people is counted once, no matter which way the path goes.
+func betweenness(people []string, adj map[string][]string) map[string]float64 {
+	result := map[string]float64{}
+	for _, id := range people {
+		result[id] = 0
+	}
+	for _, source := range people {
+		stack := []string{}
+		preds := map[string][]string{}
+		paths := map[string]float64{source: 1}
+		dist := map[string]int{source: 0}
+		queue := []string{source}
+		for len(queue) > 0 {
+			v := queue[0]
+			queue = queue[1:]
+			stack = append(stack, v)
+			for _, w := range adj[v] {
+				if _, seen := dist[w]; !seen {
+					dist[w] = dist[v] + 1
+					queue = append(queue, w)
+				}
+				if dist[w] == dist[v]+1 {
+					paths[w] += paths[v]
+					preds[w] = append(preds[w], v)
+				}
+			}
+		}
+		delta := map[string]float64{}
+		for i := len(stack) - 1; i >= 0; i-- {
+			w := stack[i]
+			for _, v := range preds[w] {
+				delta[v] += paths[v] / paths[w] * (1 + delta[w])
+			}
+			if w != source {
+				result[w] += delta[w]
+			}
+		}
+	}
+	for id := range result {
+		result[id] /= 2
+	}
+	return result
+}
+
+// cuts finds the articulation points and bridges with Tarjan's depth first search
+func cuts(people []string, adj map[string][]string) (points []string, bridges [][2]string) {
+	order := map[string]int{}
+	low := map[string]int{}
+	isPoint := map[string]bool{}
+	var visit func(v, parent string)
+	visit = func(v, parent string) {
+		order[v] = len(order) + 1
+		low[v] = order[v]
+		children := 0
+		for _, w := range adj[v] {
+			if w == parent {
+				continue
+			}
+			if order[w] != 0 {
+				low[v] = min(low[v], order[w])
+				continue
+			}
+			children++
+			visit(w, v)
+			low[v] = min(low[v], low[w])
+			if parent != "" && low[w] >= order[v] {
+				isPoint[v] = true
+			}
+			if low[w] > order[v] {
+				bridges = append(bridges, [2]string{v, w})
+			}
+		}
+		if parent == "" && children > 1 {
+			isPoint[v] = true
+		}
+	}
+	for _, id := range people {
+		if order[id] == 0 {
+			visit(id, "")
+		}
+	}
+	for _, id := range people {
+		if isPoint[id] {
+			points = append(points, id)
+		}
+	}
+	return points, bridges
+}
+
+// components groups everyone who is connected through others
+func components(people []string, adj map[string][]string) [][]string {
+	seen := map[string]bool{}
+	groups := [][]string{}
+	for _, id := range people {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		group := []string{id}
+		for i := 0; i < len(group); i++ {
+			for _, w := range adj[group[i]] {
+				if !seen[w] {
+					seen[w] = true
+					group = append(group, w)
+				}
+			}
+		}
+		groups = append(groups, group)
+	}
+	return bySize(groups, people)
+}
+
+// communities runs label propagation: everyone starts in a community of their own
+// and keeps joining the one their relations are most strongly in until nobody moves.
+// People are visited in order and ties between other communities go to the one
+// of whoever comes first, so the result is the same on every run.
+func (ix *Index) communities(people []string, adj map[string][]string) [][]string {
+	label := map[string]int{}
+	for i, id := range people {
+		label[id] = i
+	}
+	for range communityRounds {
+		moved := false
+		for _, id := range people {
+			weight := map[int]int{}
+			for _, w := range adj[id] {
+				weight[label[w]] += ix.Strength(id, w)
+			}
+			top := 0
+			for _, sum := range weight {
+				top = max(top, sum)
+			}
+			// Staying put wins a tie, that keeps two communities from swapping people forever
+			best := label[id]
+			if weight[best] < top {
+				best = len(people)
+				for l, sum := range weight {
+					if sum == top && l < best {
+						best = l
+					}
+				}
+			}
+			if best != label[id] {
+				label[id] = best
+				moved = true
+			}
+		}
+		if !moved {
+			break
+		}
+	}
+	groups := map[int][]string{}
+	for _, id := range people {
+		groups[label[id]] = append(groups[label[id]], id)
+	}
+	return bySize(slices.Collect(maps.Values(groups)), people)
+}
+
+// bySize sorts groups biggest first, groups of the same size by who comes first in people
+func bySize(groups [][]string, people []string) [][]string {
+	rank := map[string]int{}
+	for i, id := range people {
+		rank[id] = i
+	}
+	first := func(g []string) int {
+		r := len(people)
+		for _, id := range g {
+			r = min(r, rank[id])
+		}
+		return r
+	}
+	slices.SortFunc(groups, func(a, b []string) int {
+		if len(a) != len(b) {
+			return len(b) - len(a)
+		}
+		return first(a) - first(b)
+	})
+	return groups
+}
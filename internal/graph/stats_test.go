@@ -0,0 +1,83 @@
+package graph
+
+import (
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/N3moAhead/connect3/internal/relation"
+)
+
+// Two triangles a b c and d e f held together by c - d,
+// a pair h - i and g on their own
+var (
+	statsPeople    = []string{"a", "b", "c", "d", "e", "f", "g", "h", "i"}
+	statsRelations = []relation.Relation{
+		rel("ab", "a", "b", 4),
+		rel("bc", "b", "c", 4),
+		rel("ca", "c", "a", 4),
+		rel("cd", "c", "d", 2),
+		rel("de", "d", "e", 4),
+		rel("ef", "e", "f", 4),
+		rel("fd", "f", "d", 4),
+		rel("hi", "h", "i", 3),
+	}
+)
+
+func TestCuts(t *testing.T) {
+	s := New(statsRelations).Analyze(statsPeople)
+	if want := []string{"c", "d"}; !slices.Equal(s.Articulation, want) {
+		t.Fatalf("articulation points: got %v, want %v", s.Articulation, want)
+	}
+	bridges := []string{}
+	for _, b := range s.Bridges {
+		pair := []string{b[0], b[1]}
+		slices.Sort(pair)
+		bridges = append(bridges, pair[0]+"-"+pair[1])
+	}
+	slices.Sort(bridges)
+	if want := []string{"c-d", "h-i"}; !slices.Equal(bridges, want) {
+		t.Fatalf("bridges: got %v, want %v", bridges, want)
+	}
+}
+
+func TestCutsOnlyCountPeopleGiven(t *testing.T) {
+	// Without c, a - b is all that is left of the first triangle
+	s := New(statsRelations).Analyze([]string{"a", "b", "d", "e", "f"})
+	if len(s.Articulation) != 0 {
+		t.Fatalf("articulation points: got %v, want none", s.Articulation)
+	}
+	if len(s.Bridges) != 1 || !slices.Contains(s.Bridges[0][:], "a") || !slices.Contains(s.Bridges[0][:], "b") {
+		t.Fatalf("bridges: got %v, want only a - b", s.Bridges)
+	}
+}
+
+func TestDegreesAndBetweenness(t *testing.T) {
+	s := New(statsRelations).Analyze(statsPeople)
+	if s.Degree["c"] != 3 || s.WeightedDegree["c"] != 10 {
+		t.Fatalf("c: degree %d, weighted %d", s.Degree["c"], s.WeightedDegree["c"])
+	}
+	// Every path from a or b to d, e or f goes through c
+	want := map[string]float64{"a": 0, "b": 0, "c": 6, "d": 6, "e": 0, "f": 0, "g": 0, "h": 0, "i": 0}
+	if !reflect.DeepEqual(s.Betweenness, want) {
+		t.Fatalf("got %v, want %v", s.Betweenness, want)
+	}
+}
+
+func TestGroups(t *testing.T) {
+	s := New(statsRelations).Analyze(statsPeople)
+	wantComponents := [][]string{{"a", "b", "c", "d", "e", "f"}, {"h", "i"}, {"g"}}
+	for i := range s.Components {
+		slices.Sort(s.Components[i])
+	}
+	if !reflect.DeepEqual(s.Components, wantComponents) {
+		t.Fatalf("components: got %v, want %v", s.Components, wantComponents)
+	}
+	wantCommunities := [][]string{{"a", "b", "c"}, {"d", "e", "f"}, {"h", "i"}, {"g"}}
+	for i := range s.Communities {
+		slices.Sort(s.Communities[i])
+	}
+	if !reflect.DeepEqual(s.Communities, wantCommunities) {
+		t.Fatalf("communities: got %v, want %v", s.Communities, wantCommunities)
+	}
+}
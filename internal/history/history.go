@@ -0,0 +1,179 @@
+package history
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/store"
+)
+
+// Events turns the changes of one operation into history events.
+// Names for the labels come from the changes themselves first, so people
+// deleted in the same operation still get a proper label.
+func Events(r store.Reader, changes []db.Change, t time.Time) ([]db.Event, error) {
+	names := map[string]string{}
+	for _, c := range changes {
+		if c.Entity != db.EntityPerson {
+			continue
+		}
+		for _, raw := range []json.RawMessage{c.Before, c.After} {
+			fields, err := decode(raw)
+			if err != nil {
+				return nil, err
+			}
+			if name, ok := fields["name"].(string); ok {
+				names[c.ID] = name
+			}
+		}
+	}
+	nameOf := func(id string) string {
+		if name, ok := names[id]; ok {
+			return name
+		}
+		if p, err := r.GetPerson(id); err == nil {
+			return p.Name
+		}
+		return "Unknown"
+	}
+
+	// namesOf renders a json list of person IDs as "Alice, Bob"
+	namesOf := func(v any) string {
+		ids, _ := v.([]any)
+		names := make([]string, len(ids))
+		for i, id := range ids {
+			id, _ := id.(string)
+			names[i] = nameOf(id)
+		}
+		return strings.Join(names, ", ")
+	}
+
+	events := []db.Event{}
+	for _, c := range changes {
+		before, err := decode(c.Before)
+		if err != nil {
+			return nil, err
+		}
+		after, err := decode(c.After)
+		if err != nil {
+			return nil, err
+		}
+		// Whichever side exists describes the entity
+		current := after
+		if current == nil {
+			current = before
+		}
+
+		base := db.Event{Time: t, Entity: c.Entity, EntityID: c.ID}
+		switch c.Entity {
+		case db.EntityPerson:
+			base.Label = nameOf(c.ID)
+		case db.EntityRelation:
+			from, _ := current["from_id"].(string)
+			to, _ := current["to_id"].(string)
+			base.Related = []string{from, to}
+			base.Label = nameOf(from) + " - " + nameOf(to)
+		case db.EntityField:
+			base.Label = c.ID
+		case db.EntityInteraction:
+			// Everyone who took part before or after, so a removed participant still sees it
+			names := []string{}
+			for _, state := range []map[string]any{before, after} {
+				ids, _ := state["participants"].([]any)
+				for _, id := range ids {
+					id, _ := id.(string)
+					if !slices.Contains(base.Related, id) {
+						base.Related = append(base.Related, id)
+						names = append(names, nameOf(id))
+					}
+				}
+			}
+			kind, _ := current["kind"].(string)
+			base.Label = kind + " with " + strings.Join(names, ", ")
+		case db.EntityReminder:
+			id, _ := current["person_id"].(string)
+			base.Related = []string{id}
+			base.Label = "Reminder for " + nameOf(id)
+		}
+
+		switch {
+		case before == nil:
+			e := base
+			e.Action = db.ActionCreate
+			events = append(events, e)
+		case after == nil:
+			e := base
+			e.Action = db.ActionDelete
+			events = append(events, e)
+		default:
+			for _, field := range changedFields(before, after) {
+				e := base
+				e.Action = db.ActionUpdate
+				e.Field = field
+				e.Old = format(before[field])
+				e.New = format(after[field])
+				if c.Entity == db.EntityInteraction && field == "participants" {
+					e.Old, e.New = namesOf(before[field]), namesOf(after[field])
+				}
+				events = append(events, e)
+			}
+		}
+	}
+	return events, nil
+}
+
+func decode(raw json.RawMessage) (map[string]any, error) {
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	var fields map[string]any
+	err := json.Unmarshal(raw, &fields)
+	return fields, err
+}
+
+// changedFields lists every field that differs, sorted by name.
+// Working on the json keeps this in sync with new fields for free.
+func changedFields(before, after map[string]any) []string {
+	keys := map[string]bool{}
+	for k := range before {
+		keys[k] = true
+	}
+	for k := range after {
+		keys[k] = true
+	}
+	fields := []string{}
+	for k := range keys {
+		if k == "id" {
+			continue
+		}
+		if format(before[k]) != format(after[k]) {
+			fields = append(fields, k)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// format renders a json value for the history, lists become "a, b"
+func format(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []any:
+		parts := make([]string, len(v))
+		for i, item := range v {
+			parts[i] = format(item)
+		}
+		return strings.Join(parts, ", ")
+	case map[string]any:
+		raw, _ := json.Marshal(v)
+		return string(raw)
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,131 @@
+package history
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/reminder"
+	"github.com/N3moAhead/connect3/internal/store"
+)
+
+var now = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+// change records entity id going from before to after, nil for a side that does not exist
+func change(t *testing.T, entity, id string, before, after any) db.Change {
+	t.Helper()
+	c := db.Change{Entity: entity, ID: id}
+	for _, side := range []struct {
+		v   any
+		raw *json.RawMessage
+	}{{before, &c.Before}, {after, &c.After}} {
+		if side.v == nil {
+			continue
+		}
+		raw, err := json.Marshal(side.v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		*side.raw = raw
+	}
+	return c
+}
+
+// people is what the store knows, for names the changes do not carry
+func people() store.Reader {
+	return store.NewMemory(db.Database{People: []person.Person{
+		{ID: "p1", Name: "Ada"},
+		{ID: "p2", Name: "Grace"},
+		{ID: "p3", Name: "Alan"},
+	}})
+}
+
+func events(t *testing.T, changes ...db.Change) []db.Event {
+	t.Helper()
+	events, err := Events(people(), changes, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return events
+}
+
+func TestCreate(t *testing.T) {
+	got := events(t, change(t, db.EntityPerson, "p4", nil, person.Person{ID: "p4", Name: "Edsger"}))
+	want := []db.Event{{Time: now, Entity: db.EntityPerson, EntityID: "p4", Label: "Edsger", Action: db.ActionCreate}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestUpdateHasOneEventPerField(t *testing.T) {
+	before := person.Person{ID: "p1", Name: "Ada", Tags: []string{"math"}, Notes: "Met once"}
+	after := person.Person{ID: "p1", Name: "Ada Lovelace", Tags: []string{"math", "work"}, Notes: "Met once"}
+	got := events(t, change(t, db.EntityPerson, "p1", before, after))
+	// Sorted by field, lists are joined, the new name is the label
+	want := []db.Event{
+		{Time: now, Entity: db.EntityPerson, EntityID: "p1", Label: "Ada Lovelace", Action: db.ActionUpdate,
+			Field: "name", Old: "Ada", New: "Ada Lovelace"},
+		{Time: now, Entity: db.EntityPerson, EntityID: "p1", Label: "Ada Lovelace", Action: db.ActionUpdate,
+			Field: "tags", Old: "math", New: "math, work"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDeleteKeepsTheNames(t *testing.T) {
+	// The store no longer has p4, the name comes from the change deleting them
+	got := events(t,
+		change(t, db.EntityRelation, "r1", relation.Relation{ID: "r1", FromID: "p4", ToID: "p2", Strength: 3}, nil),
+		change(t, db.EntityPerson, "p4", person.Person{ID: "p4", Name: "Edsger"}, nil),
+	)
+	want := []db.Event{
+		{Time: now, Entity: db.EntityRelation, EntityID: "r1", Label: "Edsger - Grace", Related: []string{"p4", "p2"}, Action: db.ActionDelete},
+		{Time: now, Entity: db.EntityPerson, EntityID: "p4", Label: "Edsger", Action: db.ActionDelete},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestInteractionParticipants(t *testing.T) {
+	before := interaction.Interaction{ID: "i1", Date: "2024-05-01", Kind: "call", Participants: []string{"p1", "p2"}}
+	after := before
+	after.Participants = []string{"p1", "p3"}
+	got := events(t, change(t, db.EntityInteraction, "i1", before, after))
+	if len(got) != 1 {
+		t.Fatalf("got %+v, want one event", got)
+	}
+	e := got[0]
+	// Grace was taken out and still sees the change in their history
+	if e.Label != "call with Ada, Grace, Alan" || !reflect.DeepEqual(e.Related, []string{"p1", "p2", "p3"}) {
+		t.Fatalf("got label %q for %v", e.Label, e.Related)
+	}
+	if e.Field != "participants" || e.Old != "Ada, Grace" || e.New != "Ada, Alan" {
+		t.Fatalf("got %s: %q -> %q", e.Field, e.Old, e.New)
+	}
+}
+
+func TestReminderBelongsToItsPerson(t *testing.T) {
+	r := reminder.Reminder{ID: "m1", PersonID: "p2", Date: "2024-12-10", Text: "Card"}
+	got := events(t, change(t, db.EntityReminder, "m1", nil, r))
+	want := []db.Event{{Time: now, Entity: db.EntityReminder, EntityID: "m1", Label: "Reminder for Grace", Related: []string{"p2"}, Action: db.ActionCreate}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	if !got[0].Concerns("p2") || got[0].Concerns("p1") {
+		t.Fatal("the reminder event should concern Grace only")
+	}
+}
+
+func TestNothingChanged(t *testing.T) {
+	p := person.Person{ID: "p1", Name: "Ada"}
+	if got := events(t, change(t, db.EntityPerson, "p1", p, p)); len(got) != 0 {
+		t.Fatalf("got %+v, want no events", got)
+	}
+}
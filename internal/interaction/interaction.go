@@ -0,0 +1,68 @@
+package interaction
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/config"
+)
+
+// Kinds of interactions
+const (
+	KindMeeting = "meeting"
+	KindCall    = "call"
+	KindMessage = "message"
+	KindOther   = "other"
+)
+
+var Kinds = []string{KindMeeting, KindCall, KindMessage, KindOther}
+
+// Interaction is a meeting, call or message with one or more people
+type Interaction struct {
+	ID           string   `json:"id"`
+	Date         string   `json:"date"` // 2006-01-02
+	Kind         string   `json:"kind"`
+	Participants []string `json:"participants"` // IDs of the people
+	Summary      string   `json:"summary"`
+	FollowUp     string   `json:"follow_up"` // Date to get back to them, empty if there is nothing to do
+}
+
+// Check reports what is wrong with the dates and the kind
+func (i Interaction) Check() error {
+	if _, err := ParseDate(i.Date); err != nil {
+		return err
+	}
+	if i.FollowUp != "" {
+		if _, err := ParseDate(i.FollowUp); err != nil {
+			return fmt.Errorf("follow-up: %w", err)
+		}
+	}
+	if !slices.Contains(Kinds, i.Kind) {
+		return fmt.Errorf("unknown kind %q, use one of %s", i.Kind, strings.Join(Kinds, ", "))
+	}
+	return nil
+}
+
+// Involves reports if the person with the given id took part
+func (i Interaction) Involves(personID string) bool {
+	return slices.Contains(i.Participants, personID)
+}
+
+// ParseDate checks a date in the 2006-01-02 form
+func ParseDate(s string) (time.Time, error) {
+	t, err := time.Parse(config.DATE_LAYOUT, s)
+	if err != nil {
+		return t, fmt.Errorf("date %q is not in the form 2024-12-31", s)
+	}
+	return t, nil
+}
+
+// Sort orders interactions by date, oldest first. Interactions
+// on the same day keep the order they were logged in.
+func Sort(list []Interaction) {
+	slices.SortStableFunc(list, func(a, b Interaction) int {
+		return strings.Compare(a.Date, b.Date)
+	})
+}
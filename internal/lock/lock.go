@@ -0,0 +1,62 @@
+package lock
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var ErrLocked = errors.New("database is locked by another c3 instance")
+
+// Lock is an exclusive advisory lock on <db>.lock.
+// The OS drops it on its own if c3 crashes.
+type Lock struct {
+	f *os.File
+}
+
+func path(dbPath string) string { return dbPath + ".lock" }
+
+// Acquire takes the lock for dbPath without waiting.
+// If another instance holds it an error wrapping ErrLocked is returned.
+func Acquire(dbPath string) (*Lock, error) {
+	f, err := os.OpenFile(path(dbPath), os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := tryLock(f); err != nil {
+		f.Close()
+		if errors.Is(err, ErrLocked) {
+			if pid := holder(dbPath); pid != 0 {
+				return nil, fmt.Errorf("%w (pid %d)", ErrLocked, pid)
+			}
+		}
+		return nil, err
+	}
+
+	// Leave our pid for whoever runs into the lock
+	if err := f.Truncate(0); err == nil {
+		f.WriteAt([]byte(strconv.Itoa(os.Getpid())+"\n"), 0)
+	}
+	return &Lock{f: f}, nil
+}
+
+func (l *Lock) Release() error {
+	if l == nil {
+		return nil
+	}
+	l.f.Truncate(0)
+	unlock(l.f)
+	return l.f.Close()
+}
+
+// holder returns the pid written into the lock file or 0
+func holder(dbPath string) int {
+	content, err := os.ReadFile(path(dbPath))
+	if err != nil {
+		return 0
+	}
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(content)))
+	return pid
+}
@@ -0,0 +1,10 @@
+//go:build !unix
+
+package lock
+
+import "os"
+
+// No flock here, so every instance gets the lock
+func tryLock(f *os.File) error { return nil }
+
+func unlock(f *os.File) error { return nil }
@@ -0,0 +1,174 @@
+package migration
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Diff compares two json documents and returns one line per difference:
+// "+ path: value" for added, "- path: value" for removed and
+// "~ path: old -> new" for changed values
+func Diff(before, after any) ([]string, error) {
+	// Migrations may leave go types like []string behind, compare the json forms
+	before, err := normalize(before)
+	if err != nil {
+		return nil, err
+	}
+	after, err = normalize(after)
+	if err != nil {
+		return nil, err
+	}
+	lines := []string{}
+	diff("", before, after, &lines)
+	return lines, nil
+}
+
+func normalize(v any) (any, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var out any
+	err = json.Unmarshal(raw, &out)
+	return out, err
+}
+
+func diff(path string, before, after any, lines *[]string) {
+	switch b := before.(type) {
+	case map[string]any:
+		if a, ok := after.(map[string]any); ok {
+			keys := map[string]bool{}
+			for k := range b {
+				keys[k] = true
+			}
+			for k := range a {
+				keys[k] = true
+			}
+			sorted := make([]string, 0, len(keys))
+			for k := range keys {
+				sorted = append(sorted, k)
+			}
+			sort.Strings(sorted)
+			for _, k := range sorted {
+				sub := k
+				if path != "" {
+					sub = path + "." + k
+				}
+				bv, inBefore := b[k]
+				av, inAfter := a[k]
+				switch {
+				case !inBefore:
+					*lines = append(*lines, fmt.Sprintf("+ %s: %s", sub, show(av)))
+				case !inAfter:
+					*lines = append(*lines, fmt.Sprintf("- %s: %s", sub, show(bv)))
+				default:
+					diff(sub, bv, av, lines)
+				}
+			}
+			return
+		}
+	case []any:
+		if a, ok := after.([]any); ok {
+			for i := 0; i < len(b) || i < len(a); i++ {
+				sub := fmt.Sprintf("%s[%d]", path, i)
+				switch {
+				case i >= len(b):
+					*lines = append(*lines, fmt.Sprintf("+ %s: %s", sub, show(a[i])))
+				case i >= len(a):
+					*lines = append(*lines, fmt.Sprintf("- %s: %s", sub, show(b[i])))
+				default:
+					diff(sub, b[i], a[i], lines)
+				}
+			}
+			return
+		}
+	}
+	if show(before) != show(after) {
+		*lines = append(*lines, fmt.Sprintf("~ %s: %s -> %s", path, show(before), show(after)))
+	}
+}
+
+func show(v any) string {
+	raw, _ := json.Marshal(v)
+	return string(raw)
+}
+
+// Loss is what reverting a step throws away, e.g. 3 reminders or the cadence
+// of 2 people. Values still at their default are not counted, nothing is lost there.
+type Loss struct {
+	From, To string // The step being reverted, From is the newer version
+	Key      string // List of entities, e.g. "people"
+	Field    string // Empty if the whole list goes
+	Count    int
+}
+
+func (l Loss) String() string {
+	if l.Field == "" {
+		return fmt.Sprintf("%s -> %s drops %d %s", l.From, l.To, l.Count, l.Key)
+	}
+	return fmt.Sprintf("%s -> %s drops %q of %d %s", l.From, l.To, l.Field, l.Count, l.Key)
+}
+
+// lost compares the lists of entities before and after m was reverted
+func lost(m Migration, before, after map[string]any) []Loss {
+	losses := []Loss{}
+	keys := make([]string, 0, len(before))
+	for k := range before {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		old, err := entities(before, key)
+		if err != nil || len(old) == 0 {
+			continue
+		}
+		if _, ok := after[key]; !ok {
+			losses = append(losses, Loss{From: m.ToVersion, To: m.FromVersion, Key: key, Count: len(old)})
+			continue
+		}
+		kept, err := entities(after, key)
+		if err != nil {
+			continue
+		}
+		if len(kept) < len(old) {
+			losses = append(losses, Loss{From: m.ToVersion, To: m.FromVersion, Key: key, Count: len(old) - len(kept)})
+		}
+		counts := map[string]int{}
+		for i, obj := range old[:min(len(old), len(kept))] {
+			for field, value := range obj {
+				if _, ok := kept[i][field]; !ok && !blank(value) {
+					counts[field]++
+				}
+			}
+		}
+		fields := make([]string, 0, len(counts))
+		for field := range counts {
+			fields = append(fields, field)
+		}
+		sort.Strings(fields)
+		for _, field := range fields {
+			losses = append(losses, Loss{From: m.ToVersion, To: m.FromVersion, Key: key, Field: field, Count: counts[field]})
+		}
+	}
+	return losses
+}
+
+// blank reports if v is a default value like "", 0, false or an empty list
+func blank(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case float64:
+		return v == 0
+	case bool:
+		return !v
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
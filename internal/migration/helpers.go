@@ -0,0 +1,172 @@
+package migration
+
+import (
+	"fmt"
+)
+
+// Func changes the json database of one version into the next one
+type Func func(data map[string]any) (map[string]any, error)
+
+// Keys of the entity lists in the json database
+const (
+	keyPeople       = "people"
+	keyRelations    = "relations"
+	keyFields       = "fields"
+	keyInteractions = "interactions"
+	keyReminders    = "reminders"
+)
+
+// entities returns the objects in the list at key. A missing list is empty,
+// anything that is not a list of objects is an error instead of being skipped.
+func entities(data map[string]any, key string) ([]map[string]any, error) {
+	raw, ok := data[key]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	list, ok := raw.([]any)
+	if !ok {
+		return nil, fmt.Errorf("%s is not a list", key)
+	}
+	objects := make([]map[string]any, len(list))
+	for i, item := range list {
+		obj, ok := item.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("%s[%d] is not an object", key, i)
+		}
+		objects[i] = obj
+	}
+	return objects, nil
+}
+
+// Chain runs fns one after another as a single step
+func Chain(fns ...Func) Func {
+	return func(data map[string]any) (map[string]any, error) {
+		var err error
+		for _, fn := range fns {
+			if data, err = fn(data); err != nil {
+				return nil, err
+			}
+		}
+		return data, nil
+	}
+}
+
+// AddKey sets key at the top level of the database to def if it is missing,
+// e.g. for a new list of entities
+func AddKey(key string, def any) Func {
+	return func(data map[string]any) (map[string]any, error) {
+		if _, ok := data[key]; ok {
+			return data, nil
+		}
+		var err error
+		data[key], err = normalize(def)
+		return data, err
+	}
+}
+
+// DropKey removes key from the top level of the database, the revert of AddKey
+func DropKey(key string) Func {
+	return func(data map[string]any) (map[string]any, error) {
+		delete(data, key)
+		return data, nil
+	}
+}
+
+// AddField sets field to def on every entity in the list at key that does not have it yet
+func AddField(key, field string, def any) Func {
+	return func(data map[string]any) (map[string]any, error) {
+		objects, err := entities(data, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objects {
+			if _, ok := obj[field]; ok {
+				continue
+			}
+			// Every entity gets its own copy, the default may be a list or object
+			if obj[field], err = normalize(def); err != nil {
+				return nil, err
+			}
+		}
+		return data, nil
+	}
+}
+
+// DropField removes field from every entity in the list at key, the revert of AddField
+func DropField(key, field string) Func {
+	return func(data map[string]any) (map[string]any, error) {
+		objects, err := entities(data, key)
+		if err != nil {
+			return nil, err
+		}
+		for _, obj := range objects {
+			delete(obj, field)
+		}
+		return data, nil
+	}
+}
+
+// RenameField renames field from to to on every entity in the list at key
+func RenameField(key, from, to string) Func {
+	return func(data map[string]any) (map[string]any, error) {
+		objects, err := entities(data, key)
+		if err != nil {
+			return nil, err
+		}
+		for i, obj := range objects {
+			value, ok := obj[from]
+			if !ok {
+				continue
+			}
+			if _, taken := obj[to]; taken {
+				return nil, fmt.Errorf("%s[%d] already has a field %q", key, i, to)
+			}
+			obj[to] = value
+			delete(obj, from)
+		}
+		return data, nil
+	}
+}
+
+// MoveField moves field from the entities at srcKey to the entities at dstKey.
+// ref is the field of the source entity that holds the id of its target,
+// e.g. MoveField(keyRelations, "from_id", "since", keyPeople) moves "since"
+// from every relation to the person it starts at.
+// Two sources with different values for the same target are an error.
+func MoveField(srcKey, ref, field, dstKey string) Func {
+	return func(data map[string]any) (map[string]any, error) {
+		sources, err := entities(data, srcKey)
+		if err != nil {
+			return nil, err
+		}
+		targets, err := entities(data, dstKey)
+		if err != nil {
+			return nil, err
+		}
+		byID := map[string]map[string]any{}
+		for _, obj := range targets {
+			if id, ok := obj["id"].(string); ok {
+				byID[id] = obj
+			}
+		}
+		moved := map[string]any{}
+		for i, obj := range sources {
+			value, ok := obj[field]
+			if !ok {
+				continue
+			}
+			id, _ := obj[ref].(string)
+			target, ok := byID[id]
+			if !ok {
+				return nil, fmt.Errorf("%s[%d]: %s %q does not exist in %s", srcKey, i, ref, id, dstKey)
+			}
+			if prev, ok := moved[id]; ok && show(prev) != show(value) {
+				return nil, fmt.Errorf("%s[%d]: %s %q already got a different %s", srcKey, i, ref, id, field)
+			}
+			moved[id] = value
+			target[field] = value
+			delete(obj, field)
+		}
+		return data, nil
+	}
+}
@@ -0,0 +1,151 @@
+package migration
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+// The helpers no migration uses yet are tested on their own,
+// the others are covered by the fixtures
+
+func parse(t *testing.T, s string) map[string]any {
+	t.Helper()
+	var data map[string]any
+	if err := json.Unmarshal([]byte(s), &data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestRenameField(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string // Empty if an error is expected
+		wantErr bool
+	}{
+		{
+			name: "renames",
+			in:   `{"people": [{"id": "p1", "mail": "ada@example.com"}, {"id": "p2", "mail": ""}]}`,
+			want: `{"people": [{"id": "p1", "email": "ada@example.com"}, {"id": "p2", "email": ""}]}`,
+		},
+		{
+			name: "skips entities without the field",
+			in:   `{"people": [{"id": "p1", "mail": "ada@example.com"}, {"id": "p2"}]}`,
+			want: `{"people": [{"id": "p1", "email": "ada@example.com"}, {"id": "p2"}]}`,
+		},
+		{
+			name: "missing list",
+			in:   `{"version": "1.0.0"}`,
+			want: `{"version": "1.0.0"}`,
+		},
+		{
+			name:    "name already taken",
+			in:      `{"people": [{"id": "p1", "mail": "a@example.com", "email": "b@example.com"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "not a list",
+			in:      `{"people": {"id": "p1"}}`,
+			wantErr: true,
+		},
+		{
+			name:    "not an object",
+			in:      `{"people": ["p1"]}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := RenameField(keyPeople, "mail", "email")(parse(t, tt.in))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", show(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSame(t, got, parse(t, tt.want))
+
+			// Renaming back is the revert
+			back, err := RenameField(keyPeople, "email", "mail")(got)
+			if err != nil {
+				t.Fatalf("revert: %v", err)
+			}
+			assertSame(t, back, parse(t, tt.in))
+		})
+	}
+}
+
+func TestMoveField(t *testing.T) {
+	tests := []struct {
+		name    string
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "moves to the referenced entity",
+			in: `{"people": [{"id": "p1"}, {"id": "p2"}],
+			      "relations": [{"id": "r1", "from_id": "p1", "to_id": "p2", "since": "2020"}]}`,
+			want: `{"people": [{"id": "p1", "since": "2020"}, {"id": "p2"}],
+			        "relations": [{"id": "r1", "from_id": "p1", "to_id": "p2"}]}`,
+		},
+		{
+			name: "skips sources without the field",
+			in: `{"people": [{"id": "p1"}, {"id": "p2"}],
+			      "relations": [{"id": "r1", "from_id": "p1", "to_id": "p2"}]}`,
+			want: `{"people": [{"id": "p1"}, {"id": "p2"}],
+			        "relations": [{"id": "r1", "from_id": "p1", "to_id": "p2"}]}`,
+		},
+		{
+			name: "same value from two sources",
+			in: `{"people": [{"id": "p1"}, {"id": "p2"}, {"id": "p3"}],
+			      "relations": [{"id": "r1", "from_id": "p1", "to_id": "p2", "since": "2020"},
+			                    {"id": "r2", "from_id": "p1", "to_id": "p3", "since": "2020"}]}`,
+			want: `{"people": [{"id": "p1", "since": "2020"}, {"id": "p2"}, {"id": "p3"}],
+			        "relations": [{"id": "r1", "from_id": "p1", "to_id": "p2"},
+			                      {"id": "r2", "from_id": "p1", "to_id": "p3"}]}`,
+		},
+		{
+			name: "missing lists",
+			in:   `{"version": "1.0.0"}`,
+			want: `{"version": "1.0.0"}`,
+		},
+		{
+			name: "different values from two sources",
+			in: `{"people": [{"id": "p1"}, {"id": "p2"}, {"id": "p3"}],
+			      "relations": [{"id": "r1", "from_id": "p1", "to_id": "p2", "since": "2020"},
+			                    {"id": "r2", "from_id": "p1", "to_id": "p3", "since": "2021"}]}`,
+			wantErr: true,
+		},
+		{
+			name: "reference to nobody",
+			in: `{"people": [{"id": "p1"}],
+			      "relations": [{"id": "r1", "from_id": "p9", "to_id": "p1", "since": "2020"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "target is not a list",
+			in:      `{"people": "p1", "relations": []}`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := MoveField(keyRelations, "from_id", "since", keyPeople)(parse(t, tt.in))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %s", show(got))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			assertSame(t, got, parse(t, tt.want))
+		})
+	}
+}
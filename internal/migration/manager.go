@@ -2,107 +2,377 @@ package migration
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
+
+	"github.com/N3moAhead/connect3/internal/backup"
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/crypt"
+	"github.com/N3moAhead/connect3/internal/db"
 )
 
 type Migration struct {
 	FromVersion string
 	ToVersion   string
-	Apply       func(data map[string]any) (map[string]any, error)
+	Apply       Func
+	// Revert undoes Apply, it is optional. Without it the step can not be downgraded.
+	Revert Func
+	// Fixtures are test cases in testdata/<from>_to_<to>/, each one a
+	// <name>.in.json at FromVersion and the <name>.out.json Apply has to turn it into.
+	// If Revert can not give back the input, <name>.reverted.json is what it gives instead.
+	Fixtures []string
 }
 
 var migrations = []Migration{
 	{
 		FromVersion: "0.0.1",
 		ToVersion:   "1.0.0",
-		Apply:       migrate_0_0_1_to_1_0_0,
+		Apply:       AddField(keyPeople, "tags", []string{}),
+		Revert:      DropField(keyPeople, "tags"),
+		Fixtures:    []string{"basic", "keeps_tags", "empty"},
+	},
+	{
+		FromVersion: "1.0.0",
+		ToVersion:   "1.1.0",
+		Apply: Chain(
+			AddField(keyPeople, "company", ""),
+			AddField(keyPeople, "job_title", ""),
+			AddField(keyPeople, "birthday", ""),
+			AddField(keyPeople, "emails", []any{}),
+			AddField(keyPeople, "phones", []any{}),
+			AddField(keyPeople, "addresses", []any{}),
+			AddField(keyPeople, "urls", []any{}),
+		),
+		Revert: Chain(
+			DropField(keyPeople, "company"),
+			DropField(keyPeople, "job_title"),
+			DropField(keyPeople, "birthday"),
+			DropField(keyPeople, "emails"),
+			DropField(keyPeople, "phones"),
+			DropField(keyPeople, "addresses"),
+			DropField(keyPeople, "urls"),
+		),
+		Fixtures: []string{"basic", "keeps_contacts", "empty"},
+	},
+	{
+		FromVersion: "1.1.0",
+		ToVersion:   "1.2.0",
+		Apply: Chain(
+			AddKey(keyFields, []any{}),
+			AddField(keyPeople, "custom", map[string]any{}),
+		),
+		Revert: Chain(
+			DropKey(keyFields),
+			DropField(keyPeople, "custom"),
+		),
+		Fixtures: []string{"basic", "keeps_fields", "empty"},
+	},
+	{
+		FromVersion: "1.2.0",
+		ToVersion:   "1.3.0",
+		Apply:       AddKey(keyInteractions, []any{}),
+		Revert:      DropKey(keyInteractions),
+		Fixtures:    []string{"basic", "keeps_interactions", "empty"},
+	},
+	{
+		FromVersion: "1.3.0",
+		ToVersion:   "1.4.0",
+		Apply: Chain(
+			AddField(keyPeople, "cadence", 0),
+			AddField(keyPeople, "snoozed_until", ""),
+		),
+		Revert: Chain(
+			DropField(keyPeople, "cadence"),
+			DropField(keyPeople, "snoozed_until"),
+		),
+		Fixtures: []string{"basic", "keeps_cadence", "empty"},
+	},
+	{
+		FromVersion: "1.4.0",
+		ToVersion:   "1.5.0",
+		Apply:       AddKey(keyReminders, []any{}),
+		Revert:      DropKey(keyReminders),
+		Fixtures:    []string{"basic", "keeps_reminders", "empty"},
+	},
+	{
+		FromVersion: "1.5.0",
+		ToVersion:   "1.6.0",
+		Apply:       AddField(keyReminders, "snoozed_until", ""),
+		Revert:      DropField(keyReminders, "snoozed_until"),
+		Fixtures:    []string{"basic", "keeps_snoozes", "empty"},
 	},
 }
 
-// RunMigrations will always be called on startup
-func RunMigrations(dbPath string) error {
-	content, err := os.ReadFile(dbPath)
-	if os.IsNotExist(err) {
+var (
+	ErrNewerVersion = errors.New("database was written by a newer version of c3")
+	ErrMigrationGap = errors.New("no migration path")
+)
+
+// Check makes sure the registered migrations form one unbroken path
+// from the oldest version up to config.DB_FORMAT_VERSION
+func Check() error {
+	current, err := ParseVersion(config.DB_FORMAT_VERSION)
+	if err != nil {
+		return err
+	}
+	if len(migrations) == 0 {
 		return nil
 	}
+	froms := map[Version]bool{}
+	for _, m := range migrations {
+		from, err := ParseVersion(m.FromVersion)
+		if err != nil {
+			return fmt.Errorf("migration %s -> %s: %w", m.FromVersion, m.ToVersion, err)
+		}
+		to, err := ParseVersion(m.ToVersion)
+		if err != nil {
+			return fmt.Errorf("migration %s -> %s: %w", m.FromVersion, m.ToVersion, err)
+		}
+		if from.Compare(to) >= 0 {
+			return fmt.Errorf("migration %s -> %s does not go forward", from, to)
+		}
+		if to.Compare(current) > 0 {
+			return fmt.Errorf("migration %s -> %s goes past the current version %s", from, to, current)
+		}
+		if froms[from] {
+			return fmt.Errorf("two migrations start at %s", from)
+		}
+		froms[from] = true
+	}
+	// Walking from the oldest version has to use every step and end at the current one
+	steps, err := plan(oldestVersion())
 	if err != nil {
 		return err
 	}
-
-	var data map[string]any
-	if err := json.Unmarshal(content, &data); err != nil {
-		return err
+	if len(steps) != len(migrations) {
+		return fmt.Errorf("%d migrations are not on the path to %s", len(migrations)-len(steps), current)
 	}
+	return nil
+}
 
-	// getting the current version
-	currentVer, ok := data["version"].(string)
-	if !ok {
-		// Fallback if no version is provided. this case should not exist because i startet with version 0.0.1
-		// but if it happens we just set version 0.0.0 and execute all migrations
-		currentVer = "0.0.0"
+// oldestVersion is where the first migration starts
+func oldestVersion() Version {
+	oldest := MustParse(config.DB_FORMAT_VERSION)
+	for _, m := range migrations {
+		if v := MustParse(m.FromVersion); v.Compare(oldest) < 0 {
+			oldest = v
+		}
 	}
+	return oldest
+}
 
-	dirty := false
+// plan lists the steps that bring a database at version from up to date
+func plan(from Version) ([]Migration, error) {
+	steps, _, err := planTo(from, MustParse(config.DB_FORMAT_VERSION))
+	return steps, err
+}
 
-	for {
-		var foundMigration *Migration
-		for _, m := range migrations {
-			if m.FromVersion == currentVer {
-				foundMigration = &m
+// planTo lists the steps from one version to another.
+// down is set if the steps have to be reverted, newest first.
+func planTo(from, to Version) (steps []Migration, down bool, err error) {
+	current := MustParse(config.DB_FORMAT_VERSION)
+	if from.Compare(current) > 0 {
+		return nil, false, fmt.Errorf("%w: the file is at %s, this c3 supports up to %s", ErrNewerVersion, from, current)
+	}
+	if to.Compare(current) > 0 {
+		return nil, false, fmt.Errorf("this c3 only knows versions up to %s, not %s", current, to)
+	}
+	down = to.Compare(from) < 0
+	steps = []Migration{}
+	for v := from; v.Compare(to) != 0; {
+		var next *Migration
+		for i, m := range migrations {
+			edge := m.FromVersion
+			if down {
+				edge = m.ToVersion
+			}
+			if MustParse(edge).Compare(v) == 0 {
+				next = &migrations[i]
 				break
 			}
 		}
-
-		if foundMigration == nil {
-			break
+		if down {
+			if next == nil || MustParse(next.FromVersion).Compare(to) < 0 {
+				return nil, true, fmt.Errorf("%w from %s down to %s", ErrMigrationGap, from, to)
+			}
+			if next.Revert == nil {
+				return nil, true, fmt.Errorf("migration %s -> %s can not be reverted", next.FromVersion, next.ToVersion)
+			}
+			v = MustParse(next.FromVersion)
+		} else {
+			if next == nil || MustParse(next.ToVersion).Compare(to) > 0 {
+				return nil, false, fmt.Errorf("%w from %s to %s", ErrMigrationGap, v, to)
+			}
+			v = MustParse(next.ToVersion)
 		}
+		steps = append(steps, *next)
+	}
+	return steps, down, nil
+}
 
-		fmt.Printf("Migrating DB from %s to %s...\n", currentVer, foundMigration.ToVersion)
+// Preview is what migrating a database would do, worked out on a copy
+type Preview struct {
+	From   Version
+	To     Version
+	Steps  []Migration
+	Down   bool // The steps are reverted
+	Before map[string]any
+	After  map[string]any
+	Losses []Loss // What the reverted steps throw away, empty going up
+}
 
-		newData, err := foundMigration.Apply(data)
-		if err != nil {
-			return fmt.Errorf("migration failed: %v", err)
-		}
+// Prepare runs the pending migrations on a copy of the database at dbPath.
+// It returns nil if there is no database yet.
+func Prepare(dbPath string) (*Preview, error) {
+	return PrepareTo(dbPath, MustParse(config.DB_FORMAT_VERSION))
+}
 
-		data = newData
-		currentVer = foundMigration.ToVersion
+// PrepareTo is Prepare for any version this c3 knows, older ones included
+func PrepareTo(dbPath string, target Version) (*Preview, error) {
+	content, err := crypt.ReadFile(dbPath)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
 
-		data["version"] = currentVer
-		dirty = true
+	var before, data map[string]any
+	if err := json.Unmarshal(content, &before); err != nil {
+		return nil, db.DescribeJSONError(dbPath, content, err)
+	}
+	// Migrations change the maps in place, so they get their own copy
+	if err := json.Unmarshal(content, &data); err != nil {
+		return nil, err
 	}
 
-	// Save the db if something changed...
-	if dirty {
-		newContent, _ := json.MarshalIndent(data, "", " ")
-		return os.WriteFile(dbPath, newContent, 0644)
+	// getting the current version
+	var currentVer Version
+	if raw, ok := data["version"].(string); ok {
+		if currentVer, err = ParseVersion(raw); err != nil {
+			return nil, fmt.Errorf("%s: %w", dbPath, err)
+		}
+	} else {
+		// Fallback if no version is provided. this case should not exist because i startet with version 0.0.1
+		// but if it happens we start at the oldest version and execute all migrations
+		currentVer = oldestVersion()
 	}
 
-	return nil
-}
+	steps, down, err := planTo(currentVer, target)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", dbPath, err)
+	}
 
-// --- Migrations ---
+	data, losses, err := runSteps(data, steps, down)
+	if err != nil {
+		return nil, err
+	}
+	return &Preview{From: currentVer, To: target, Steps: steps, Down: down, Before: before, After: data, Losses: losses}, nil
+}
 
-func migrate_0_0_1_to_1_0_0(data map[string]any) (map[string]any, error) {
-	peopleRaw, ok := data["people"].([]any)
-	if !ok {
-		return data, nil
+// runSteps applies the steps to data, or reverts them if down is set and
+// counts what each revert drops. After every step the database is checked for new problems.
+func runSteps(data map[string]any, steps []Migration, down bool) (map[string]any, []Loss, error) {
+	// Problems the file already had are not the fault of the migrations
+	known := map[string]bool{}
+	if problems, err := validate(data); err == nil {
+		for _, p := range problems {
+			known[p.String()] = true
+		}
 	}
 
-	// Iterate through each person and add tag field
-	for i, p := range peopleRaw {
-		personMap, ok := p.(map[string]any)
-		if !ok {
-			continue
+	losses := []Loss{}
+	for i, m := range steps {
+		var err error
+		if down {
+			// Revert changes the maps in place, keep a copy to compare with
+			copied, err := normalize(data)
+			if err != nil {
+				return nil, nil, err
+			}
+			if data, err = m.Revert(data); err != nil {
+				return nil, nil, fmt.Errorf("reverting migration %s -> %s failed: %w", m.FromVersion, m.ToVersion, err)
+			}
+			data["version"] = m.FromVersion
+			losses = append(losses, lost(m, copied.(map[string]any), data)...)
+		} else {
+			if data, err = m.Apply(data); err != nil {
+				return nil, nil, fmt.Errorf("migration %s -> %s failed: %w", m.FromVersion, m.ToVersion, err)
+			}
+			data["version"] = m.ToVersion
 		}
 
-		if _, hasTags := personMap["tags"]; !hasTags {
-			personMap["tags"] = []string{}
+		if err := checkStep(m, data, known, i == len(steps)-1); err != nil {
+			return nil, nil, err
+		}
+	}
+	return data, losses, nil
+}
+
+// validate decodes data the way the store does and runs db.Validate on it
+func validate(data map[string]any) ([]db.Problem, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	var database db.Database
+	if err := json.Unmarshal(raw, &database); err != nil {
+		return nil, err
+	}
+	return db.Validate(database), nil
+}
+
+// checkStep fails if migration m left problems behind that were not there before.
+// Only the last step has to decode into today's types, versions in between may not.
+func checkStep(m Migration, data map[string]any, known map[string]bool, last bool) error {
+	problems, err := validate(data)
+	if err != nil {
+		if !last {
+			return nil
 		}
+		return fmt.Errorf("migration %s -> %s produced an unreadable database: %w", m.FromVersion, m.ToVersion, err)
+	}
+	for _, p := range problems {
+		if !known[p.String()] {
+			return fmt.Errorf("migration %s -> %s broke the database: %s", m.FromVersion, m.ToVersion, p)
+		}
+	}
+	return nil
+}
 
-		peopleRaw[i] = personMap
+// RunMigrations will always be called on startup
+func RunMigrations(dbPath string) error {
+	return MigrateTo(dbPath, MustParse(config.DB_FORMAT_VERSION))
+}
+
+// MigrateTo brings the database at dbPath to the target version,
+// reverting migrations if the target is older than the file
+func MigrateTo(dbPath string, target Version) error {
+	p, err := PrepareTo(dbPath, target)
+	if err != nil || p == nil || len(p.Steps) == 0 {
+		return err
+	}
+	for _, m := range p.Steps {
+		if p.Down {
+			fmt.Printf("Reverting DB from %s to %s...\n", m.ToVersion, m.FromVersion)
+		} else {
+			fmt.Printf("Migrating DB from %s to %s...\n", m.FromVersion, m.ToVersion)
+		}
 	}
 
-	data["people"] = peopleRaw
-	return data, nil
+	newContent, err := json.MarshalIndent(p.After, "", " ")
+	if err != nil {
+		return fmt.Errorf("encoding migrated db: %w", err)
+	}
+	// Keep the original named after its version. Labeled backups are never pruned,
+	// so a failed migration can always be rolled back.
+	b, err := backup.Create(dbPath, "pre-migration-"+p.From.String())
+	if err != nil {
+		return err
+	}
+	if b != nil {
+		fmt.Printf("Saved the %s database as %s\n", p.From, b.Path)
+	}
+	return crypt.WriteFile(dbPath, newContent, 0644)
 }
@@ -0,0 +1,151 @@
+package migration
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/db"
+)
+
+// The tests below are driven by the migrations list and their fixtures,
+// a new migration only needs its fixture files to be covered.
+
+func fixturePath(m Migration, name, suffix string) string {
+	return filepath.Join("testdata", m.FromVersion+"_to_"+m.ToVersion, name+suffix)
+}
+
+func readFixture(t *testing.T, path string) map[string]any {
+	t.Helper()
+	content, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var data map[string]any
+	if err := json.Unmarshal(content, &data); err != nil {
+		t.Fatalf("%s: %v", path, err)
+	}
+	return data
+}
+
+func assertSame(t *testing.T, got, want map[string]any) {
+	t.Helper()
+	g, err := normalize(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := normalize(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(g, w) {
+		lines, _ := Diff(w, g)
+		t.Fatalf("result differs from the fixture:\n%s", strings.Join(lines, "\n"))
+	}
+}
+
+func TestCheck(t *testing.T) {
+	if err := Check(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestFixtures runs every fixture through its own step, and back if the step can be reverted
+func TestFixtures(t *testing.T) {
+	for _, m := range migrations {
+		if len(m.Fixtures) == 0 {
+			t.Errorf("migration %s -> %s has no fixtures", m.FromVersion, m.ToVersion)
+		}
+		for _, name := range m.Fixtures {
+			t.Run(m.FromVersion+"_to_"+m.ToVersion+"/"+name, func(t *testing.T) {
+				want := readFixture(t, fixturePath(m, name, ".out.json"))
+				got, _, err := runSteps(readFixture(t, fixturePath(m, name, ".in.json")), []Migration{m}, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				assertSame(t, got, want)
+
+				if m.Revert == nil {
+					return
+				}
+				reverted, _, err := runSteps(readFixture(t, fixturePath(m, name, ".out.json")), []Migration{m}, true)
+				if err != nil {
+					t.Fatalf("revert: %v", err)
+				}
+				// Reverting gives back the input, or <name>.reverted.json if the revert loses data
+				wantReverted := fixturePath(m, name, ".reverted.json")
+				if _, err := os.Stat(wantReverted); err != nil {
+					wantReverted = fixturePath(m, name, ".in.json")
+				}
+				assertSame(t, reverted, readFixture(t, wantReverted))
+			})
+		}
+	}
+}
+
+// TestFixturesReachCurrent runs every fixture through the whole chain and
+// checks that the result fits today's types exactly and passes validation
+func TestFixturesReachCurrent(t *testing.T) {
+	for _, m := range migrations {
+		for _, name := range m.Fixtures {
+			t.Run(m.FromVersion+"_to_"+config.DB_FORMAT_VERSION+"/"+name, func(t *testing.T) {
+				steps, err := plan(MustParse(m.FromVersion))
+				if err != nil {
+					t.Fatal(err)
+				}
+				data, _, err := runSteps(readFixture(t, fixturePath(m, name, ".in.json")), steps, false)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if data["version"] != config.DB_FORMAT_VERSION {
+					t.Fatalf("ended at version %v", data["version"])
+				}
+
+				raw, err := json.Marshal(data)
+				if err != nil {
+					t.Fatal(err)
+				}
+				dec := json.NewDecoder(bytes.NewReader(raw))
+				dec.DisallowUnknownFields()
+				var database db.Database
+				if err := dec.Decode(&database); err != nil {
+					t.Fatalf("result does not fit db.Database: %v", err)
+				}
+				if problems := db.Validate(database); len(problems) > 0 {
+					t.Fatalf("result has problems: %v", problems)
+				}
+			})
+		}
+	}
+}
+
+func TestDowngradeCountsLosses(t *testing.T) {
+	steps, down, err := planTo(MustParse("1.6.0"), MustParse("1.4.0"))
+	if err != nil || !down {
+		t.Fatalf("planning the downgrade: %v (down %v)", err, down)
+	}
+	data := readFixture(t, filepath.Join("testdata", "1.5.0_to_1.6.0", "keeps_snoozes.out.json"))
+	_, losses, err := runSteps(data, steps, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only one reminder is snoozed, the other one still has the default
+	want := []Loss{
+		{From: "1.6.0", To: "1.5.0", Key: "reminders", Field: "snoozed_until", Count: 1},
+		{From: "1.5.0", To: "1.4.0", Key: "reminders", Count: 2},
+	}
+	if !reflect.DeepEqual(losses, want) {
+		t.Fatalf("got %v, want %v", losses, want)
+	}
+
+	// Empty lists and default values lose nothing
+	data = readFixture(t, filepath.Join("testdata", "1.5.0_to_1.6.0", "basic.out.json"))
+	if _, losses, err = runSteps(data, steps, true); err != nil || len(losses) != 0 {
+		t.Fatalf("got %v (%v), want no losses", losses, err)
+	}
+}
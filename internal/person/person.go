@@ -1,13 +1,92 @@
 package person
 
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/config"
+)
+
 type Person struct {
 	ID    string   `json:"id"`
 	Name  string   `json:"name"`
 	Notes string   `json:"notes"`
 	Tags  []string `json:"tags"`
+
+	// Contact details
+	Company   string  `json:"company"`
+	JobTitle  string  `json:"job_title"`
+	Birthday  string  `json:"birthday"` // 2006-01-02, or --01-02 if the year is unknown
+	Emails    []Entry `json:"emails"`
+	Phones    []Entry `json:"phones"`
+	Addresses []Entry `json:"addresses"`
+	URLs      []Entry `json:"urls"` // Websites and social handles
+
+	// Values of the custom fields by field name, see db.FieldDef
+	Custom map[string]string `json:"custom"`
+
+	// Keep in touch
+	Cadence      int    `json:"cadence"`       // Days between contacts, 0 if there is no goal
+	SnoozedUntil string `json:"snoozed_until"` // 2006-01-02, not overdue before that day
+}
+
+// Entry is one labeled contact detail, e.g. work: ada@example.com
+type Entry struct {
+	Label string `json:"label"`
+	Value string `json:"value"`
+}
+
+// Kinds of contact entries, in the order they are shown
+const (
+	KindEmail   = "email"
+	KindPhone   = "phone"
+	KindAddress = "address"
+	KindURL     = "url"
+)
+
+var Kinds = []string{KindEmail, KindPhone, KindAddress, KindURL}
+
+// Entries returns the list that holds entries of kind
+func (p *Person) Entries(kind string) *[]Entry {
+	switch kind {
+	case KindEmail:
+		return &p.Emails
+	case KindPhone:
+		return &p.Phones
+	case KindAddress:
+		return &p.Addresses
+	case KindURL:
+		return &p.URLs
+	}
+	return nil
 }
 
 // Implement list.Item interface
 func (p Person) Title() string       { return p.Name }
 func (p Person) Description() string { return p.Notes }
 func (p Person) FilterValue() string { return p.Name }
+
+// Snoozed reports if the person is snoozed on the day today
+func (p Person) Snoozed(today time.Time) bool {
+	until, err := time.Parse(config.DATE_LAYOUT, p.SnoozedUntil)
+	return err == nil && until.After(today)
+}
+
+// ParseBirthday checks a birthday in the 2006-01-02 or --01-02 form.
+// The year is 0 if it is unknown.
+func ParseBirthday(s string) (year int, month time.Month, day int, err error) {
+	layout, value := config.DATE_LAYOUT, s
+	if strings.HasPrefix(s, "--") {
+		// Any leap year, so --02-29 is fine
+		layout, value = "2000-01-02", "2000-"+s[2:]
+	}
+	t, err := time.Parse(layout, value)
+	if err != nil {
+		return 0, 0, 0, fmt.Errorf("birthday %q is not in the form 1990-12-31 or --12-31", s)
+	}
+	if layout == config.DATE_LAYOUT {
+		year = t.Year()
+	}
+	return year, t.Month(), t.Day(), nil
+}
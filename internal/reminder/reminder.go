@@ -0,0 +1,96 @@
+package reminder
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/config"
+)
+
+// How often a reminder comes back
+const (
+	RepeatNone   = ""
+	RepeatYearly = "yearly" // e.g. anniversaries
+)
+
+// Reminder is something to do about a person on a given day
+type Reminder struct {
+	ID           string `json:"id"`
+	PersonID     string `json:"person_id"`
+	Date         string `json:"date"` // 2006-01-02, the day it is due
+	Text         string `json:"text"`
+	Repeat       string `json:"repeat"` // RepeatNone or RepeatYearly
+	Done         bool   `json:"done"`
+	SnoozedUntil string `json:"snoozed_until"` // 2006-01-02, due then instead, Date keeps the anniversary
+}
+
+// Check reports what is wrong with the dates and the repeat
+func (r Reminder) Check() error {
+	if _, err := time.Parse(config.DATE_LAYOUT, r.Date); err != nil {
+		return fmt.Errorf("date %q is not in the form 2024-12-31", r.Date)
+	}
+	if r.Repeat != RepeatNone && r.Repeat != RepeatYearly {
+		return fmt.Errorf("unknown repeat %q, use %q or leave it empty", r.Repeat, RepeatYearly)
+	}
+	if r.SnoozedUntil != "" {
+		if _, err := time.Parse(config.DATE_LAYOUT, r.SnoozedUntil); err != nil {
+			return fmt.Errorf("snoozed until %q, which is not a date", r.SnoozedUntil)
+		}
+	}
+	return nil
+}
+
+// Due returns the day the reminder is due, the snooze if it is later than the date
+func (r Reminder) Due() (time.Time, error) {
+	date, err := time.Parse(config.DATE_LAYOUT, r.Date)
+	if err != nil {
+		return date, err
+	}
+	if until, err := time.Parse(config.DATE_LAYOUT, r.SnoozedUntil); err == nil && until.After(date) {
+		return until, nil
+	}
+	return date, nil
+}
+
+// Complete marks the reminder as done. A yearly one moves on to its next
+// date instead, at least a year on and after today. Either way the snooze is over.
+func (r *Reminder) Complete(today time.Time) {
+	r.SnoozedUntil = ""
+	if r.Repeat != RepeatYearly {
+		r.Done = true
+		return
+	}
+	date, err := time.Parse(config.DATE_LAYOUT, r.Date)
+	if err != nil {
+		r.Done = true
+		return
+	}
+	next := addYears(date, 1)
+	for years := 2; !next.After(today); years++ {
+		next = addYears(date, years)
+	}
+	r.Date = next.Format(config.DATE_LAYOUT)
+}
+
+// addYears moves date years on, see Anniversary
+func addYears(date time.Time, years int) time.Time {
+	year, month, day := date.Date()
+	return Anniversary(year+years, month, day, date.Location())
+}
+
+// Anniversary returns month and day in year. A day the month does not have in
+// that year becomes its last day, so Feb 29 falls on Feb 28 instead of Mar 1.
+func Anniversary(year int, month time.Month, day int, loc *time.Location) time.Time {
+	last := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	return time.Date(year, month, min(day, last), 0, 0, 0, 0, loc)
+}
+
+// Snooze makes the reminder due days after today. It only ever defers,
+// a reminder that is due later than that stays as it is.
+func (r *Reminder) Snooze(today time.Time, days int) {
+	until := today.AddDate(0, 0, days)
+	if due, err := r.Due(); err == nil && due.After(until) {
+		return
+	}
+	r.SnoozedUntil = until.Format(config.DATE_LAYOUT)
+}
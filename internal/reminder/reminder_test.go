@@ -0,0 +1,87 @@
+package reminder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/config"
+)
+
+func day(t *testing.T, s string) time.Time {
+	t.Helper()
+	d, err := time.Parse(config.DATE_LAYOUT, s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return d
+}
+
+func TestCompleteYearly(t *testing.T) {
+	tests := []struct {
+		name        string
+		date, today string
+		want        string
+	}{
+		{"on the day", "2024-06-01", "2024-06-01", "2025-06-01"},
+		{"ahead of time", "2024-12-10", "2024-12-05", "2025-12-10"},
+		{"years late", "2020-06-01", "2024-01-01", "2024-06-01"},
+		{"leap day", "2024-02-29", "2024-02-29", "2025-02-28"},
+		{"leap day years late", "2024-02-29", "2027-03-01", "2028-02-29"},
+		{"end of month", "2023-01-31", "2023-01-31", "2024-01-31"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Reminder{Date: tt.date, Repeat: RepeatYearly}
+			r.Complete(day(t, tt.today))
+			if r.Done || r.Date != tt.want {
+				t.Fatalf("got %s (done %v), want %s", r.Date, r.Done, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteOnce(t *testing.T) {
+	r := Reminder{Date: "2024-06-01"}
+	r.Complete(day(t, "2024-06-01"))
+	if !r.Done || r.Date != "2024-06-01" {
+		t.Fatalf("got %+v, want it done on the same date", r)
+	}
+}
+
+func TestSnoozeOnlyDefers(t *testing.T) {
+	tests := []struct {
+		name        string
+		date, until string
+		want        string
+	}{
+		{"overdue", "2024-06-01", "", "2024-06-11"},
+		{"due today", "2024-06-10", "", "2024-06-11"},
+		{"not due yet", "2024-06-20", "", "2024-06-20"},
+		{"snoozed further", "2024-06-01", "2024-06-15", "2024-06-15"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := Reminder{Date: tt.date, SnoozedUntil: tt.until}
+			r.Snooze(day(t, "2024-06-10"), 1)
+			due, err := r.Due()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := due.Format(config.DATE_LAYOUT); got != tt.want {
+				t.Fatalf("due %s, want %s", got, tt.want)
+			}
+			if r.Date != tt.date {
+				t.Fatalf("date moved to %s", r.Date)
+			}
+		})
+	}
+}
+
+func TestSnoozeKeepsTheAnniversary(t *testing.T) {
+	r := Reminder{Date: "2024-06-01", Repeat: RepeatYearly}
+	r.Snooze(day(t, "2024-06-05"), 1)
+	r.Complete(day(t, "2024-06-06"))
+	if r.Date != "2025-06-01" || r.SnoozedUntil != "" {
+		t.Fatalf("got %s snoozed until %q, want 2025-06-01 and no snooze", r.Date, r.SnoozedUntil)
+	}
+}
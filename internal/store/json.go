@@ -0,0 +1,180 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/crypt"
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/migration"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/reminder"
+	"github.com/google/uuid"
+)
+
+// JSONStore keeps the database in memory and rewrites
+// the whole json file on every commit
+type JSONStore struct {
+	*MemoryStore
+	path  string
+	stamp fileStamp // What the file looked like when we last read or wrote it
+
+	// BeforeSave runs before the file is rewritten, e.g. to take a backup.
+	// If it fails nothing is saved.
+	BeforeSave func() error
+
+	// Problems db.Validate found when the file was loaded
+	Problems []db.Problem
+}
+
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+func stampOf(path string) (fileStamp, error) {
+	info, err := os.Stat(path)
+	if os.IsNotExist(err) {
+		return fileStamp{}, nil
+	}
+	if err != nil {
+		return fileStamp{}, err
+	}
+	return fileStamp{modTime: info.ModTime(), size: info.Size()}, nil
+}
+
+func OpenJSON(dbPath string) (*JSONStore, error) {
+	database, problems, err := loadData(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	s := &JSONStore{
+		MemoryStore: NewMemory(database),
+		path:        dbPath,
+		Problems:    problems,
+	}
+	s.persist = func(database db.Database) error {
+		if s.BeforeSave != nil {
+			if err := s.BeforeSave(); err != nil {
+				return err
+			}
+		}
+		if err := saveData(database, s.path); err != nil {
+			return err
+		}
+		// Our own writes are no outside changes
+		s.stamp, err = stampOf(s.path)
+		return err
+	}
+	if s.stamp, err = stampOf(dbPath); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *JSONStore) Changed() (bool, error) {
+	stamp, err := stampOf(s.path)
+	if err != nil {
+		return false, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return stamp != s.stamp, nil
+}
+
+func (s *JSONStore) Reload() error {
+	// Stamp first, if the file changes while we read we just reload again
+	stamp, err := stampOf(s.path)
+	if err != nil {
+		return err
+	}
+	database, err := readData(s.path)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = database
+	s.Problems = db.Validate(database)
+	s.stamp = stamp
+	return nil
+}
+
+// readData reads the json database. A missing file is an empty database,
+// a broken one is an error so we never overwrite it with nothing. So is a
+// file a newer c3 wrote, we would drop what we do not know on the next save.
+func readData(dbPath string) (db.Database, error) {
+	content, err := crypt.ReadFile(dbPath)
+	if os.IsNotExist(err) {
+		return db.Database{
+			People:       []person.Person{},
+			Relations:    []relation.Relation{},
+			Fields:       []db.FieldDef{},
+			Interactions: []interaction.Interaction{},
+			Reminders:    []reminder.Reminder{},
+			Version:      config.DB_FORMAT_VERSION,
+		}, nil
+	}
+	if err != nil {
+		return db.Database{}, err
+	}
+	var database db.Database
+	if err := json.Unmarshal(content, &database); err != nil {
+		return db.Database{}, db.DescribeJSONError(dbPath, content, err)
+	}
+	if database.Version != "" {
+		version, err := migration.ParseVersion(database.Version)
+		if err != nil {
+			return db.Database{}, fmt.Errorf("%s: %w", dbPath, err)
+		}
+		if current := config.DB_FORMAT_VERSION; version.Compare(migration.MustParse(current)) > 0 {
+			return db.Database{}, fmt.Errorf("%w: the file is at %s, this c3 supports up to %s", migration.ErrNewerVersion, version, current)
+		}
+	}
+	return database, nil
+}
+
+// loadData reads the json database, repairs missing relation IDs
+// and validates the result
+func loadData(dbPath string) (db.Database, []db.Problem, error) {
+	database, err := readData(dbPath)
+	if err != nil {
+		return database, nil, err
+	}
+
+	if fillRelationIDs(database) {
+		if err := saveData(database, dbPath); err != nil {
+			return database, nil, err
+		}
+	}
+	return database, db.Validate(database), nil
+}
+
+// fillRelationIDs gives relations from before relation IDs one,
+// it reports if there were any
+func fillRelationIDs(database db.Database) bool {
+	dirty := false
+	for i := range database.Relations {
+		if database.Relations[i].ID == "" {
+			database.Relations[i].ID = uuid.New().String()
+			dirty = true
+		}
+	}
+	return dirty
+}
+
+func saveData(database db.Database, dbPath string) error {
+	file, err := json.MarshalIndent(database, "", " ")
+	if err != nil {
+		return fmt.Errorf("encoding database: %w", err)
+	}
+	if err := crypt.WriteFile(dbPath, file, 0644); err != nil {
+		return fmt.Errorf("saving %s: %w", dbPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,591 @@
+package store
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/reminder"
+)
+
+// MemoryStore keeps the whole database in memory.
+// It is used on its own for testing and as the base of the json store.
+type MemoryStore struct {
+	mu   sync.Mutex
+	data db.Database
+	// persist is called with the new state before a transaction is committed
+	persist func(database db.Database) error
+}
+
+func NewMemory(database db.Database) *MemoryStore {
+	if database.Version == "" {
+		database.Version = config.DB_FORMAT_VERSION
+	}
+	return &MemoryStore{data: cloneDatabase(database)}
+}
+
+func (s *MemoryStore) Update(fn func(tx Tx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Work on a copy so a failing transaction leaves no traces
+	tx := &memTx{data: cloneDatabase(s.data)}
+	if err := fn(tx); err != nil {
+		return err
+	}
+	if s.persist != nil {
+		if err := s.persist(tx.data); err != nil {
+			return err
+		}
+	}
+	s.data = tx.data
+	return nil
+}
+
+func (s *MemoryStore) Snapshot() (db.Database, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return cloneDatabase(s.data), nil
+}
+
+func (s *MemoryStore) Close() error { return nil }
+
+func (s *MemoryStore) view(fn func(tx *memTx) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return fn(&memTx{data: s.data})
+}
+
+// --- Store shortcuts ---
+
+func (s *MemoryStore) GetPerson(id string) (p person.Person, err error) {
+	err = s.view(func(tx *memTx) error {
+		p, err = tx.GetPerson(id)
+		return err
+	})
+	return p, err
+}
+
+func (s *MemoryStore) ListPeople() (people []person.Person, err error) {
+	err = s.view(func(tx *memTx) error {
+		people, err = tx.ListPeople()
+		return err
+	})
+	return people, err
+}
+
+func (s *MemoryStore) GetRelation(id string) (r relation.Relation, err error) {
+	err = s.view(func(tx *memTx) error {
+		r, err = tx.GetRelation(id)
+		return err
+	})
+	return r, err
+}
+
+func (s *MemoryStore) ListRelations() (rels []relation.Relation, err error) {
+	err = s.view(func(tx *memTx) error {
+		rels, err = tx.ListRelations()
+		return err
+	})
+	return rels, err
+}
+
+func (s *MemoryStore) GetField(name string) (f db.FieldDef, err error) {
+	err = s.view(func(tx *memTx) error {
+		f, err = tx.GetField(name)
+		return err
+	})
+	return f, err
+}
+
+func (s *MemoryStore) ListFields() (fields []db.FieldDef, err error) {
+	err = s.view(func(tx *memTx) error {
+		fields, err = tx.ListFields()
+		return err
+	})
+	return fields, err
+}
+
+func (s *MemoryStore) GetInteraction(id string) (i interaction.Interaction, err error) {
+	err = s.view(func(tx *memTx) error {
+		i, err = tx.GetInteraction(id)
+		return err
+	})
+	return i, err
+}
+
+func (s *MemoryStore) ListInteractions() (list []interaction.Interaction, err error) {
+	err = s.view(func(tx *memTx) error {
+		list, err = tx.ListInteractions()
+		return err
+	})
+	return list, err
+}
+
+func (s *MemoryStore) GetReminder(id string) (r reminder.Reminder, err error) {
+	err = s.view(func(tx *memTx) error {
+		r, err = tx.GetReminder(id)
+		return err
+	})
+	return r, err
+}
+
+func (s *MemoryStore) ListReminders() (list []reminder.Reminder, err error) {
+	err = s.view(func(tx *memTx) error {
+		list, err = tx.ListReminders()
+		return err
+	})
+	return list, err
+}
+
+func (s *MemoryStore) UndoLog() (log db.UndoLog, err error) {
+	err = s.view(func(tx *memTx) error {
+		log, err = tx.UndoLog()
+		return err
+	})
+	return log, err
+}
+
+func (s *MemoryStore) SaveUndoLog(log db.UndoLog) error {
+	return s.Update(func(tx Tx) error { return tx.SaveUndoLog(log) })
+}
+
+func (s *MemoryStore) AppendEvents(events []db.Event) error {
+	return s.Update(func(tx Tx) error { return tx.AppendEvents(events) })
+}
+
+func (s *MemoryStore) ListEvents(personID string) (events []db.Event, err error) {
+	err = s.view(func(tx *memTx) error {
+		events, err = tx.ListEvents(personID)
+		return err
+	})
+	return events, err
+}
+
+func (s *MemoryStore) CreatePerson(p person.Person) error {
+	return s.Update(func(tx Tx) error { return tx.CreatePerson(p) })
+}
+
+func (s *MemoryStore) UpdatePerson(p person.Person) error {
+	return s.Update(func(tx Tx) error { return tx.UpdatePerson(p) })
+}
+
+func (s *MemoryStore) DeletePerson(id string) error {
+	return s.Update(func(tx Tx) error { return tx.DeletePerson(id) })
+}
+
+func (s *MemoryStore) CreateRelation(r relation.Relation) error {
+	return s.Update(func(tx Tx) error { return tx.CreateRelation(r) })
+}
+
+func (s *MemoryStore) UpdateRelation(r relation.Relation) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateRelation(r) })
+}
+
+func (s *MemoryStore) DeleteRelation(id string) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteRelation(id) })
+}
+
+func (s *MemoryStore) CreateField(f db.FieldDef) error {
+	return s.Update(func(tx Tx) error { return tx.CreateField(f) })
+}
+
+func (s *MemoryStore) UpdateField(f db.FieldDef) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateField(f) })
+}
+
+func (s *MemoryStore) DeleteField(name string) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteField(name) })
+}
+
+func (s *MemoryStore) CreateInteraction(i interaction.Interaction) error {
+	return s.Update(func(tx Tx) error { return tx.CreateInteraction(i) })
+}
+
+func (s *MemoryStore) UpdateInteraction(i interaction.Interaction) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateInteraction(i) })
+}
+
+func (s *MemoryStore) DeleteInteraction(id string) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteInteraction(id) })
+}
+
+func (s *MemoryStore) CreateReminder(r reminder.Reminder) error {
+	return s.Update(func(tx Tx) error { return tx.CreateReminder(r) })
+}
+
+func (s *MemoryStore) UpdateReminder(r reminder.Reminder) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateReminder(r) })
+}
+
+func (s *MemoryStore) DeleteReminder(id string) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteReminder(id) })
+}
+
+// --- Transaction ---
+
+type memTx struct {
+	data db.Database
+}
+
+func (tx *memTx) personIndex(id string) int {
+	for i, p := range tx.data.People {
+		if p.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (tx *memTx) relationIndex(id string) int {
+	for i, r := range tx.data.Relations {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (tx *memTx) fieldIndex(name string) int {
+	for i, f := range tx.data.Fields {
+		if strings.EqualFold(f.Name, name) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (tx *memTx) interactionIndex(id string) int {
+	for i, in := range tx.data.Interactions {
+		if in.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (tx *memTx) reminderIndex(id string) int {
+	for i, r := range tx.data.Reminders {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (tx *memTx) GetPerson(id string) (person.Person, error) {
+	i := tx.personIndex(id)
+	if i < 0 {
+		return person.Person{}, fmt.Errorf("person %s: %w", id, ErrNotFound)
+	}
+	return clonePerson(tx.data.People[i]), nil
+}
+
+func (tx *memTx) ListPeople() ([]person.Person, error) {
+	people := make([]person.Person, len(tx.data.People))
+	for i, p := range tx.data.People {
+		people[i] = clonePerson(p)
+	}
+	return people, nil
+}
+
+func (tx *memTx) GetRelation(id string) (relation.Relation, error) {
+	i := tx.relationIndex(id)
+	if i < 0 {
+		return relation.Relation{}, fmt.Errorf("relation %s: %w", id, ErrNotFound)
+	}
+	return tx.data.Relations[i], nil
+}
+
+func (tx *memTx) ListRelations() ([]relation.Relation, error) {
+	return append([]relation.Relation{}, tx.data.Relations...), nil
+}
+
+func (tx *memTx) CreatePerson(p person.Person) error {
+	if tx.personIndex(p.ID) >= 0 {
+		return fmt.Errorf("person %s already exists", p.ID)
+	}
+	tx.data.People = append(tx.data.People, clonePerson(p))
+	return nil
+}
+
+func (tx *memTx) UpdatePerson(p person.Person) error {
+	i := tx.personIndex(p.ID)
+	if i < 0 {
+		return fmt.Errorf("person %s: %w", p.ID, ErrNotFound)
+	}
+	tx.data.People[i] = clonePerson(p)
+	return nil
+}
+
+func (tx *memTx) DeletePerson(id string) error {
+	i := tx.personIndex(id)
+	if i < 0 {
+		return fmt.Errorf("person %s: %w", id, ErrNotFound)
+	}
+	newRels := []relation.Relation{}
+	for _, r := range tx.data.Relations {
+		if r.FromID != id && r.ToID != id {
+			newRels = append(newRels, r)
+		}
+	}
+	tx.data.Relations = newRels
+	newInteractions := []interaction.Interaction{}
+	for _, in := range tx.data.Interactions {
+		if in.Involves(id) {
+			in = cloneInteraction(in)
+			in.Participants = slices.DeleteFunc(in.Participants, func(p string) bool { return p == id })
+			if len(in.Participants) == 0 {
+				continue
+			}
+		}
+		newInteractions = append(newInteractions, in)
+	}
+	tx.data.Interactions = newInteractions
+	tx.data.Reminders = slices.DeleteFunc(tx.data.Reminders, func(r reminder.Reminder) bool {
+		return r.PersonID == id
+	})
+	tx.data.People = append(tx.data.People[:i], tx.data.People[i+1:]...)
+	return nil
+}
+
+func (tx *memTx) CreateRelation(r relation.Relation) error {
+	if tx.relationIndex(r.ID) >= 0 {
+		return fmt.Errorf("relation %s already exists", r.ID)
+	}
+	tx.data.Relations = append(tx.data.Relations, r)
+	return nil
+}
+
+func (tx *memTx) UpdateRelation(r relation.Relation) error {
+	i := tx.relationIndex(r.ID)
+	if i < 0 {
+		return fmt.Errorf("relation %s: %w", r.ID, ErrNotFound)
+	}
+	tx.data.Relations[i] = r
+	return nil
+}
+
+func (tx *memTx) DeleteRelation(id string) error {
+	i := tx.relationIndex(id)
+	if i < 0 {
+		return fmt.Errorf("relation %s: %w", id, ErrNotFound)
+	}
+	tx.data.Relations = append(tx.data.Relations[:i], tx.data.Relations[i+1:]...)
+	return nil
+}
+
+func (tx *memTx) GetField(name string) (db.FieldDef, error) {
+	i := tx.fieldIndex(name)
+	if i < 0 {
+		return db.FieldDef{}, fmt.Errorf("field %s: %w", name, ErrNotFound)
+	}
+	return cloneField(tx.data.Fields[i]), nil
+}
+
+func (tx *memTx) ListFields() ([]db.FieldDef, error) {
+	fields := make([]db.FieldDef, len(tx.data.Fields))
+	for i, f := range tx.data.Fields {
+		fields[i] = cloneField(f)
+	}
+	return fields, nil
+}
+
+func (tx *memTx) CreateField(f db.FieldDef) error {
+	if tx.fieldIndex(f.Name) >= 0 {
+		return fmt.Errorf("field %s already exists", f.Name)
+	}
+	tx.data.Fields = append(tx.data.Fields, cloneField(f))
+	return nil
+}
+
+func (tx *memTx) UpdateField(f db.FieldDef) error {
+	i := tx.fieldIndex(f.Name)
+	if i < 0 {
+		return fmt.Errorf("field %s: %w", f.Name, ErrNotFound)
+	}
+	tx.data.Fields[i] = cloneField(f)
+	return nil
+}
+
+func (tx *memTx) DeleteField(name string) error {
+	i := tx.fieldIndex(name)
+	if i < 0 {
+		return fmt.Errorf("field %s: %w", name, ErrNotFound)
+	}
+	tx.data.Fields = append(tx.data.Fields[:i], tx.data.Fields[i+1:]...)
+	return nil
+}
+
+func (tx *memTx) GetInteraction(id string) (interaction.Interaction, error) {
+	i := tx.interactionIndex(id)
+	if i < 0 {
+		return interaction.Interaction{}, fmt.Errorf("interaction %s: %w", id, ErrNotFound)
+	}
+	return cloneInteraction(tx.data.Interactions[i]), nil
+}
+
+func (tx *memTx) ListInteractions() ([]interaction.Interaction, error) {
+	list := make([]interaction.Interaction, len(tx.data.Interactions))
+	for i, in := range tx.data.Interactions {
+		list[i] = cloneInteraction(in)
+	}
+	return list, nil
+}
+
+func (tx *memTx) CreateInteraction(in interaction.Interaction) error {
+	if tx.interactionIndex(in.ID) >= 0 {
+		return fmt.Errorf("interaction %s already exists", in.ID)
+	}
+	tx.data.Interactions = append(tx.data.Interactions, cloneInteraction(in))
+	return nil
+}
+
+func (tx *memTx) UpdateInteraction(in interaction.Interaction) error {
+	i := tx.interactionIndex(in.ID)
+	if i < 0 {
+		return fmt.Errorf("interaction %s: %w", in.ID, ErrNotFound)
+	}
+	tx.data.Interactions[i] = cloneInteraction(in)
+	return nil
+}
+
+func (tx *memTx) DeleteInteraction(id string) error {
+	i := tx.interactionIndex(id)
+	if i < 0 {
+		return fmt.Errorf("interaction %s: %w", id, ErrNotFound)
+	}
+	tx.data.Interactions = append(tx.data.Interactions[:i], tx.data.Interactions[i+1:]...)
+	return nil
+}
+
+func (tx *memTx) GetReminder(id string) (reminder.Reminder, error) {
+	i := tx.reminderIndex(id)
+	if i < 0 {
+		return reminder.Reminder{}, fmt.Errorf("reminder %s: %w", id, ErrNotFound)
+	}
+	return tx.data.Reminders[i], nil
+}
+
+func (tx *memTx) ListReminders() ([]reminder.Reminder, error) {
+	return append([]reminder.Reminder{}, tx.data.Reminders...), nil
+}
+
+func (tx *memTx) CreateReminder(r reminder.Reminder) error {
+	if tx.reminderIndex(r.ID) >= 0 {
+		return fmt.Errorf("reminder %s already exists", r.ID)
+	}
+	tx.data.Reminders = append(tx.data.Reminders, r)
+	return nil
+}
+
+func (tx *memTx) UpdateReminder(r reminder.Reminder) error {
+	i := tx.reminderIndex(r.ID)
+	if i < 0 {
+		return fmt.Errorf("reminder %s: %w", r.ID, ErrNotFound)
+	}
+	tx.data.Reminders[i] = r
+	return nil
+}
+
+func (tx *memTx) DeleteReminder(id string) error {
+	i := tx.reminderIndex(id)
+	if i < 0 {
+		return fmt.Errorf("reminder %s: %w", id, ErrNotFound)
+	}
+	tx.data.Reminders = append(tx.data.Reminders[:i], tx.data.Reminders[i+1:]...)
+	return nil
+}
+
+func (tx *memTx) UndoLog() (db.UndoLog, error) {
+	if tx.data.Undo == nil {
+		return db.UndoLog{}, nil
+	}
+	return cloneUndoLog(*tx.data.Undo), nil
+}
+
+func (tx *memTx) SaveUndoLog(log db.UndoLog) error {
+	log = cloneUndoLog(log)
+	tx.data.Undo = &log
+	return nil
+}
+
+func (tx *memTx) AppendEvents(events []db.Event) error {
+	tx.data.Events = append(tx.data.Events, events...)
+	return nil
+}
+
+func (tx *memTx) ListEvents(personID string) ([]db.Event, error) {
+	events := []db.Event{}
+	for _, e := range tx.data.Events {
+		if personID == "" || e.Concerns(personID) {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// --- Helpers ---
+
+func clonePerson(p person.Person) person.Person {
+	p.Tags = append([]string{}, p.Tags...)
+	for _, kind := range person.Kinds {
+		entries := p.Entries(kind)
+		*entries = append([]person.Entry{}, *entries...)
+	}
+	p.Custom = maps.Clone(p.Custom)
+	if p.Custom == nil {
+		p.Custom = map[string]string{}
+	}
+	return p
+}
+
+func cloneField(f db.FieldDef) db.FieldDef {
+	f.Options = slices.Clone(f.Options)
+	return f
+}
+
+func cloneInteraction(in interaction.Interaction) interaction.Interaction {
+	in.Participants = append([]string{}, in.Participants...)
+	return in
+}
+
+func cloneDatabase(database db.Database) db.Database {
+	clone := database
+	clone.People = make([]person.Person, len(database.People))
+	for i, p := range database.People {
+		clone.People[i] = clonePerson(p)
+	}
+	clone.Relations = append([]relation.Relation{}, database.Relations...)
+	clone.Fields = make([]db.FieldDef, len(database.Fields))
+	for i, f := range database.Fields {
+		clone.Fields[i] = cloneField(f)
+	}
+	clone.Interactions = make([]interaction.Interaction, len(database.Interactions))
+	for i, in := range database.Interactions {
+		clone.Interactions[i] = cloneInteraction(in)
+	}
+	clone.Reminders = append([]reminder.Reminder{}, database.Reminders...)
+	if database.Undo != nil {
+		log := cloneUndoLog(*database.Undo)
+		clone.Undo = &log
+	}
+	// Events are never modified, sharing them is fine as long as appends do not
+	clone.Events = database.Events[:len(database.Events):len(database.Events)]
+	return clone
+}
+
+// cloneUndoLog copies the operation lists, the changes themselves are never modified
+func cloneUndoLog(log db.UndoLog) db.UndoLog {
+	return db.UndoLog{
+		Undo: append([]db.Operation{}, log.Undo...),
+		Redo: append([]db.Operation{}, log.Redo...),
+	}
+}
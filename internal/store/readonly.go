@@ -0,0 +1,83 @@
+package store
+
+import (
+	"errors"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/reminder"
+)
+
+var ErrReadOnly = errors.New("database is opened read-only")
+
+// readOnlyStore lets reads through and refuses every write
+type readOnlyStore struct {
+	Store
+}
+
+// ReadOnly wraps s so that all writes fail with ErrReadOnly
+func ReadOnly(s Store) Store {
+	return readOnlyStore{Store: s}
+}
+
+func (readOnlyStore) Update(fn func(tx Tx) error) error                 { return ErrReadOnly }
+func (readOnlyStore) CreatePerson(p person.Person) error                { return ErrReadOnly }
+func (readOnlyStore) UpdatePerson(p person.Person) error                { return ErrReadOnly }
+func (readOnlyStore) DeletePerson(id string) error                      { return ErrReadOnly }
+func (readOnlyStore) CreateRelation(r relation.Relation) error          { return ErrReadOnly }
+func (readOnlyStore) UpdateRelation(r relation.Relation) error          { return ErrReadOnly }
+func (readOnlyStore) DeleteRelation(id string) error                    { return ErrReadOnly }
+func (readOnlyStore) CreateField(f db.FieldDef) error                   { return ErrReadOnly }
+func (readOnlyStore) UpdateField(f db.FieldDef) error                   { return ErrReadOnly }
+func (readOnlyStore) DeleteField(name string) error                     { return ErrReadOnly }
+func (readOnlyStore) CreateInteraction(i interaction.Interaction) error { return ErrReadOnly }
+func (readOnlyStore) UpdateInteraction(i interaction.Interaction) error { return ErrReadOnly }
+func (readOnlyStore) DeleteInteraction(id string) error                 { return ErrReadOnly }
+func (readOnlyStore) CreateReminder(r reminder.Reminder) error          { return ErrReadOnly }
+func (readOnlyStore) UpdateReminder(r reminder.Reminder) error          { return ErrReadOnly }
+func (readOnlyStore) DeleteReminder(id string) error                    { return ErrReadOnly }
+func (readOnlyStore) SaveUndoLog(log db.UndoLog) error                  { return ErrReadOnly }
+func (readOnlyStore) AppendEvents(events []db.Event) error              { return ErrReadOnly }
+
+func (s readOnlyStore) Changed() (bool, error) {
+	if r, ok := s.Store.(Reloader); ok {
+		return r.Changed()
+	}
+	return false, nil
+}
+
+func (s readOnlyStore) Reload() error {
+	if r, ok := s.Store.(Reloader); ok {
+		return r.Reload()
+	}
+	return nil
+}
+
+// OpenReadOnly opens dbPath without ever writing to it
+func OpenReadOnly(dbPath string) (Store, error) {
+	if IsSQLite(dbPath) {
+		s, err := openSQLiteReadOnly(dbPath)
+		if err != nil {
+			return nil, err
+		}
+		return ReadOnly(s), nil
+	}
+	database, err := readData(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	// readData refuses newer files, older ones are migrated in memory only
+	if database.Version != config.DB_FORMAT_VERSION {
+		if database, err = readMigrated(dbPath); err != nil {
+			return nil, err
+		}
+	}
+	s := &JSONStore{MemoryStore: NewMemory(database), path: dbPath}
+	if s.stamp, err = stampOf(dbPath); err != nil {
+		return nil, err
+	}
+	return ReadOnly(s), nil
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"bytes"
+	"encoding/json"
+	"regexp"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/crypt"
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/reminder"
+)
+
+var versionPattern = regexp.MustCompile(`"version"\s*:\s*"([^"]*)"`)
+
+// Salvage pulls every intact person, relation, field, interaction and reminder object out
+// of a broken json file. It tries to decode an object at every '{' and keeps the ones that
+// look like a person (id + name), a relation (id + from_id + to_id), a field (name + type),
+// an interaction (id + participants) or a reminder (id + person_id).
+func Salvage(content []byte) db.Database {
+	database := db.Database{
+		People:       []person.Person{},
+		Relations:    []relation.Relation{},
+		Fields:       []db.FieldDef{},
+		Interactions: []interaction.Interaction{},
+		Reminders:    []reminder.Reminder{},
+		Version:      config.DB_FORMAT_VERSION,
+	}
+	if match := versionPattern.FindSubmatch(content); match != nil {
+		database.Version = string(match[1])
+	}
+
+	seenPeople := map[string]bool{}
+	seenRels := map[string]bool{}
+	seenFields := map[string]bool{}
+	seenInteractions := map[string]bool{}
+	seenReminders := map[string]bool{}
+	for i := 0; i < len(content); i++ {
+		if content[i] != '{' {
+			continue
+		}
+		dec := json.NewDecoder(bytes.NewReader(content[i:]))
+		var raw map[string]json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			continue
+		}
+
+		_, hasID := raw["id"]
+		_, hasName := raw["name"]
+		_, hasFrom := raw["from_id"]
+		_, hasTo := raw["to_id"]
+		_, hasType := raw["type"]
+		_, hasParticipants := raw["participants"]
+		_, hasPerson := raw["person_id"]
+		obj := content[i : i+int(dec.InputOffset())]
+		switch {
+		case hasID && hasName:
+			var p person.Person
+			if json.Unmarshal(obj, &p) != nil || p.ID == "" || seenPeople[p.ID] {
+				continue
+			}
+			if p.Tags == nil {
+				p.Tags = []string{}
+			}
+			seenPeople[p.ID] = true
+			database.People = append(database.People, p)
+		case hasID && hasFrom && hasTo:
+			var r relation.Relation
+			if json.Unmarshal(obj, &r) != nil || r.ID == "" || seenRels[r.ID] {
+				continue
+			}
+			seenRels[r.ID] = true
+			database.Relations = append(database.Relations, r)
+		case !hasID && hasName && hasType:
+			var f db.FieldDef
+			if json.Unmarshal(obj, &f) != nil || f.Name == "" || seenFields[f.Name] {
+				continue
+			}
+			seenFields[f.Name] = true
+			database.Fields = append(database.Fields, f)
+		case hasID && hasParticipants:
+			var in interaction.Interaction
+			if json.Unmarshal(obj, &in) != nil || in.ID == "" || seenInteractions[in.ID] {
+				continue
+			}
+			seenInteractions[in.ID] = true
+			database.Interactions = append(database.Interactions, in)
+		case hasID && hasPerson:
+			var r reminder.Reminder
+			if json.Unmarshal(obj, &r) != nil || r.ID == "" || seenReminders[r.ID] {
+				continue
+			}
+			seenReminders[r.ID] = true
+			database.Reminders = append(database.Reminders, r)
+		default:
+			// Not an entity (e.g. the whole file if it was valid), look inside of it
+			continue
+		}
+		// Skip over the object we just took
+		i += int(dec.InputOffset()) - 1
+	}
+	return database
+}
+
+// Recover salvages what it can from a broken json database and writes it to outPath.
+// The broken file is only read.
+func Recover(dbPath, outPath string) (db.Database, error) {
+	content, err := crypt.ReadFile(dbPath)
+	if err != nil {
+		return db.Database{}, err
+	}
+	database := Salvage(content)
+	return database, saveData(database, outPath)
+}
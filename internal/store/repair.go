@@ -0,0 +1,35 @@
+package store
+
+import (
+	"github.com/N3moAhead/connect3/internal/db"
+)
+
+// Repair runs db.Fix on the database at dbPath and saves the result.
+// It returns what was fixed and what is still left to fix by hand.
+func Repair(dbPath string) (fixed, left []db.Problem, err error) {
+	var database db.Database
+	var save func(db.Database) error
+	if IsSQLite(dbPath) {
+		s, err := OpenSQLite(dbPath)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer s.Close()
+		if database, err = s.Snapshot(); err != nil {
+			return nil, nil, err
+		}
+		save = s.Import
+	} else {
+		if database, err = readData(dbPath); err != nil {
+			return nil, nil, err
+		}
+		save = func(database db.Database) error { return saveData(database, dbPath) }
+	}
+
+	database, fixed = db.Fix(database)
+	left = db.Validate(database)
+	if len(fixed) == 0 {
+		return fixed, left, nil
+	}
+	return fixed, left, save(database)
+}
@@ -0,0 +1,1118 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/migration"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/reminder"
+	_ "modernc.org/sqlite" // pure go driver, no cgo needed
+)
+
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS meta (
+	key   TEXT PRIMARY KEY,
+	value TEXT NOT NULL
+);
+CREATE TABLE IF NOT EXISTS people (
+	id    TEXT PRIMARY KEY,
+	name  TEXT NOT NULL,
+	notes TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS tags (
+	person_id TEXT NOT NULL,
+	tag       TEXT NOT NULL,
+	position  INTEGER NOT NULL,
+	PRIMARY KEY (person_id, tag)
+);
+CREATE TABLE IF NOT EXISTS relations (
+	id          TEXT PRIMARY KEY,
+	from_id     TEXT NOT NULL,
+	to_id       TEXT NOT NULL,
+	strength    INTEGER NOT NULL,
+	description TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE IF NOT EXISTS events (
+	seq       INTEGER PRIMARY KEY AUTOINCREMENT,
+	time      TEXT NOT NULL,
+	entity    TEXT NOT NULL,
+	entity_id TEXT NOT NULL,
+	label     TEXT NOT NULL DEFAULT '',
+	related   TEXT NOT NULL DEFAULT '[]',
+	action    TEXT NOT NULL,
+	field     TEXT NOT NULL DEFAULT '',
+	old_value TEXT NOT NULL DEFAULT '',
+	new_value TEXT NOT NULL DEFAULT ''
+);
+CREATE INDEX IF NOT EXISTS events_entity ON events(entity_id);
+CREATE INDEX IF NOT EXISTS relations_from ON relations(from_id);
+CREATE INDEX IF NOT EXISTS relations_to ON relations(to_id);
+`
+
+// sqliteSchema is the schema of sqliteBaseVersion. Later versions are reached
+// through sqliteUpgrades, so new and old files end up with the same schema.
+const sqliteBaseVersion = "1.0.0"
+
+var sqliteUpgrades = []struct {
+	version string
+	schema  string
+}{
+	{"1.1.0", `
+ALTER TABLE people ADD COLUMN company   TEXT NOT NULL DEFAULT '';
+ALTER TABLE people ADD COLUMN job_title TEXT NOT NULL DEFAULT '';
+ALTER TABLE people ADD COLUMN birthday  TEXT NOT NULL DEFAULT '';
+CREATE TABLE contacts (
+	person_id TEXT NOT NULL,
+	kind      TEXT NOT NULL,
+	label     TEXT NOT NULL DEFAULT '',
+	value     TEXT NOT NULL,
+	position  INTEGER NOT NULL
+);
+CREATE INDEX contacts_person ON contacts(person_id);
+`},
+	{"1.2.0", `
+CREATE TABLE fields (
+	name    TEXT PRIMARY KEY COLLATE NOCASE,
+	type    TEXT NOT NULL,
+	options TEXT NOT NULL DEFAULT '[]'
+);
+CREATE TABLE custom_values (
+	person_id TEXT NOT NULL,
+	field     TEXT NOT NULL,
+	value     TEXT NOT NULL,
+	PRIMARY KEY (person_id, field)
+);
+`},
+	{"1.3.0", `
+CREATE TABLE interactions (
+	id        TEXT PRIMARY KEY,
+	date      TEXT NOT NULL,
+	kind      TEXT NOT NULL,
+	summary   TEXT NOT NULL DEFAULT '',
+	follow_up TEXT NOT NULL DEFAULT ''
+);
+CREATE TABLE participants (
+	interaction_id TEXT NOT NULL,
+	person_id      TEXT NOT NULL,
+	position       INTEGER NOT NULL,
+	PRIMARY KEY (interaction_id, person_id)
+);
+CREATE INDEX participants_person ON participants(person_id);
+`},
+	{"1.4.0", `
+ALTER TABLE people ADD COLUMN cadence       INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE people ADD COLUMN snoozed_until TEXT NOT NULL DEFAULT '';
+`},
+	{"1.5.0", `
+CREATE TABLE reminders (
+	id        TEXT PRIMARY KEY,
+	person_id TEXT NOT NULL,
+	date      TEXT NOT NULL,
+	text      TEXT NOT NULL DEFAULT '',
+	repeat    TEXT NOT NULL DEFAULT '',
+	done      INTEGER NOT NULL DEFAULT 0
+);
+CREATE INDEX reminders_person ON reminders(person_id);
+`},
+	{"1.6.0", `
+ALTER TABLE reminders ADD COLUMN snoozed_until TEXT NOT NULL DEFAULT '';
+`},
+}
+
+// SQLiteStore writes every change as a single row update
+// instead of rewriting the whole database
+type SQLiteStore struct {
+	db *sql.DB
+	// dataVersion changes whenever another connection commits
+	dataVersion int64
+}
+
+func OpenSQLite(dbPath string) (*SQLiteStore, error) {
+	conn, err := sql.Open("sqlite", dbPath+"?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+	// sqlite only allows one writer anyways
+	conn.SetMaxOpenConns(1)
+
+	if _, err := conn.Exec(sqliteSchema); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("creating schema: %w", err)
+	}
+	_, err = conn.Exec(`INSERT OR IGNORE INTO meta (key, value) VALUES ('version', ?)`, sqliteBaseVersion)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	s := &SQLiteStore{db: conn}
+	if err := s.upgrade(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if s.dataVersion, err = s.queryDataVersion(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// openSQLiteReadOnly opens dbPath on a read only connection. The schema is
+// neither created nor upgraded, so the file has to be up to date already.
+func openSQLiteReadOnly(dbPath string) (*SQLiteStore, error) {
+	// sqlite would create a missing file even in read only mode
+	if _, err := os.Stat(dbPath); err != nil {
+		return nil, err
+	}
+	conn, err := sql.Open("sqlite", "file:"+dbPath+"?mode=ro&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	conn.SetMaxOpenConns(1)
+
+	var raw string
+	if err := conn.QueryRow(`SELECT value FROM meta WHERE key = 'version'`).Scan(&raw); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("reading the schema version: %w", err)
+	}
+	if current := config.DB_FORMAT_VERSION; raw != current {
+		conn.Close()
+		version, err := migration.ParseVersion(raw)
+		if err != nil {
+			return nil, err
+		}
+		if version.Compare(migration.MustParse(current)) > 0 {
+			return nil, fmt.Errorf("%w: the file is at %s, this c3 supports up to %s", migration.ErrNewerVersion, version, current)
+		}
+		return nil, fmt.Errorf("the schema is at %s, open the database once with write access to upgrade it to %s", version, current)
+	}
+	s := &SQLiteStore{db: conn}
+	if s.dataVersion, err = s.queryDataVersion(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// upgrade runs the schema upgrades the file has not seen yet
+func (s *SQLiteStore) upgrade() error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var raw string
+	if err := tx.QueryRow(`SELECT value FROM meta WHERE key = 'version'`).Scan(&raw); err != nil {
+		return err
+	}
+	version, err := migration.ParseVersion(raw)
+	if err != nil {
+		return err
+	}
+	if current := config.DB_FORMAT_VERSION; version.Compare(migration.MustParse(current)) > 0 {
+		return fmt.Errorf("%w: the file is at %s, this c3 supports up to %s", migration.ErrNewerVersion, version, current)
+	}
+	for _, u := range sqliteUpgrades {
+		if version.Compare(migration.MustParse(u.version)) >= 0 {
+			continue
+		}
+		if _, err := tx.Exec(u.schema); err != nil {
+			return fmt.Errorf("upgrading schema to %s: %w", u.version, err)
+		}
+	}
+	if _, err := tx.Exec(`UPDATE meta SET value = ? WHERE key = 'version'`, config.DB_FORMAT_VERSION); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (s *SQLiteStore) queryDataVersion() (int64, error) {
+	var v int64
+	err := s.db.QueryRow(`PRAGMA data_version`).Scan(&v)
+	return v, err
+}
+
+func (s *SQLiteStore) Changed() (bool, error) {
+	v, err := s.queryDataVersion()
+	if err != nil {
+		return false, err
+	}
+	return v != s.dataVersion, nil
+}
+
+// Reload only has to remember the version, every read goes to the file anyways
+func (s *SQLiteStore) Reload() error {
+	v, err := s.queryDataVersion()
+	if err != nil {
+		return err
+	}
+	s.dataVersion = v
+	return nil
+}
+
+func (s *SQLiteStore) Update(fn func(tx Tx) error) error {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	if err := fn(&sqlTx{q: tx}); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// Snapshot reads everything in one transaction, so a write from another
+// process can not land between two of the tables
+func (s *SQLiteStore) Snapshot() (db.Database, error) {
+	var database db.Database
+	tx, err := s.db.Begin()
+	if err != nil {
+		return database, err
+	}
+	// Nothing is written, rolling back just ends the read
+	defer tx.Rollback()
+	if err := tx.QueryRow(`SELECT value FROM meta WHERE key = 'version'`).Scan(&database.Version); err != nil {
+		return database, err
+	}
+	reader := &sqlTx{q: tx}
+	if database.People, err = reader.ListPeople(); err != nil {
+		return database, err
+	}
+	if database.Relations, err = reader.ListRelations(); err != nil {
+		return database, err
+	}
+	if database.Fields, err = reader.ListFields(); err != nil {
+		return database, err
+	}
+	if database.Interactions, err = reader.ListInteractions(); err != nil {
+		return database, err
+	}
+	if database.Reminders, err = reader.ListReminders(); err != nil {
+		return database, err
+	}
+	log, err := reader.UndoLog()
+	if err != nil {
+		return database, err
+	}
+	if len(log.Undo) > 0 || len(log.Redo) > 0 {
+		database.Undo = &log
+	}
+	if database.Events, err = reader.ListEvents(""); err != nil {
+		return database, err
+	}
+	return database, nil
+}
+
+func (s *SQLiteStore) Close() error { return s.db.Close() }
+
+// BackupTo writes a consistent copy of the database to path
+func (s *SQLiteStore) BackupTo(path string) error {
+	_, err := s.db.Exec(`VACUUM INTO ?`, path)
+	return err
+}
+
+// Import replaces the whole content of the store with the given database, IDs are kept.
+// The database has to be at the current version: meta.version is the version of the
+// schema, which is already up to date, so older data has to be migrated first.
+func (s *SQLiteStore) Import(database db.Database) error {
+	if database.Version != "" && database.Version != config.DB_FORMAT_VERSION {
+		return fmt.Errorf("can not import a database at version %s, migrate it to %s first", database.Version, config.DB_FORMAT_VERSION)
+	}
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, table := range []string{"tags", "contacts", "custom_values", "relations", "people", "fields", "participants", "interactions", "reminders", "events"} {
+		if _, err := tx.Exec("DELETE FROM " + table); err != nil {
+			return err
+		}
+	}
+	t := &sqlTx{q: tx}
+	for _, p := range database.People {
+		if err := t.CreatePerson(p); err != nil {
+			return err
+		}
+	}
+	for _, r := range database.Relations {
+		if err := t.CreateRelation(r); err != nil {
+			return err
+		}
+	}
+	for _, f := range database.Fields {
+		if err := t.CreateField(f); err != nil {
+			return err
+		}
+	}
+	for _, i := range database.Interactions {
+		if err := t.CreateInteraction(i); err != nil {
+			return err
+		}
+	}
+	for _, r := range database.Reminders {
+		if err := t.CreateReminder(r); err != nil {
+			return err
+		}
+	}
+	log := db.UndoLog{}
+	if database.Undo != nil {
+		log = *database.Undo
+	}
+	if err := t.SaveUndoLog(log); err != nil {
+		return err
+	}
+	if err := t.AppendEvents(database.Events); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// --- Store shortcuts ---
+
+func (s *SQLiteStore) GetPerson(id string) (person.Person, error) {
+	return (&sqlTx{q: s.db}).GetPerson(id)
+}
+
+func (s *SQLiteStore) ListPeople() ([]person.Person, error) {
+	return (&sqlTx{q: s.db}).ListPeople()
+}
+
+func (s *SQLiteStore) GetRelation(id string) (relation.Relation, error) {
+	return (&sqlTx{q: s.db}).GetRelation(id)
+}
+
+func (s *SQLiteStore) ListRelations() ([]relation.Relation, error) {
+	return (&sqlTx{q: s.db}).ListRelations()
+}
+
+func (s *SQLiteStore) GetField(name string) (db.FieldDef, error) {
+	return (&sqlTx{q: s.db}).GetField(name)
+}
+
+func (s *SQLiteStore) ListFields() ([]db.FieldDef, error) {
+	return (&sqlTx{q: s.db}).ListFields()
+}
+
+func (s *SQLiteStore) GetInteraction(id string) (interaction.Interaction, error) {
+	return (&sqlTx{q: s.db}).GetInteraction(id)
+}
+
+func (s *SQLiteStore) ListInteractions() ([]interaction.Interaction, error) {
+	return (&sqlTx{q: s.db}).ListInteractions()
+}
+
+func (s *SQLiteStore) GetReminder(id string) (reminder.Reminder, error) {
+	return (&sqlTx{q: s.db}).GetReminder(id)
+}
+
+func (s *SQLiteStore) ListReminders() ([]reminder.Reminder, error) {
+	return (&sqlTx{q: s.db}).ListReminders()
+}
+
+func (s *SQLiteStore) UndoLog() (db.UndoLog, error) {
+	return (&sqlTx{q: s.db}).UndoLog()
+}
+
+func (s *SQLiteStore) SaveUndoLog(log db.UndoLog) error {
+	return s.Update(func(tx Tx) error { return tx.SaveUndoLog(log) })
+}
+
+func (s *SQLiteStore) AppendEvents(events []db.Event) error {
+	return s.Update(func(tx Tx) error { return tx.AppendEvents(events) })
+}
+
+func (s *SQLiteStore) ListEvents(personID string) ([]db.Event, error) {
+	return (&sqlTx{q: s.db}).ListEvents(personID)
+}
+
+func (s *SQLiteStore) CreatePerson(p person.Person) error {
+	return s.Update(func(tx Tx) error { return tx.CreatePerson(p) })
+}
+
+func (s *SQLiteStore) UpdatePerson(p person.Person) error {
+	return s.Update(func(tx Tx) error { return tx.UpdatePerson(p) })
+}
+
+func (s *SQLiteStore) DeletePerson(id string) error {
+	return s.Update(func(tx Tx) error { return tx.DeletePerson(id) })
+}
+
+func (s *SQLiteStore) CreateRelation(r relation.Relation) error {
+	return s.Update(func(tx Tx) error { return tx.CreateRelation(r) })
+}
+
+func (s *SQLiteStore) UpdateRelation(r relation.Relation) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateRelation(r) })
+}
+
+func (s *SQLiteStore) DeleteRelation(id string) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteRelation(id) })
+}
+
+func (s *SQLiteStore) CreateField(f db.FieldDef) error {
+	return s.Update(func(tx Tx) error { return tx.CreateField(f) })
+}
+
+func (s *SQLiteStore) UpdateField(f db.FieldDef) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateField(f) })
+}
+
+func (s *SQLiteStore) DeleteField(name string) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteField(name) })
+}
+
+func (s *SQLiteStore) CreateInteraction(i interaction.Interaction) error {
+	return s.Update(func(tx Tx) error { return tx.CreateInteraction(i) })
+}
+
+func (s *SQLiteStore) UpdateInteraction(i interaction.Interaction) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateInteraction(i) })
+}
+
+func (s *SQLiteStore) DeleteInteraction(id string) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteInteraction(id) })
+}
+
+func (s *SQLiteStore) CreateReminder(r reminder.Reminder) error {
+	return s.Update(func(tx Tx) error { return tx.CreateReminder(r) })
+}
+
+func (s *SQLiteStore) UpdateReminder(r reminder.Reminder) error {
+	return s.Update(func(tx Tx) error { return tx.UpdateReminder(r) })
+}
+
+func (s *SQLiteStore) DeleteReminder(id string) error {
+	return s.Update(func(tx Tx) error { return tx.DeleteReminder(id) })
+}
+
+// --- Transaction ---
+
+// querier is implemented by *sql.DB and *sql.Tx
+type querier interface {
+	Exec(query string, args ...any) (sql.Result, error)
+	Query(query string, args ...any) (*sql.Rows, error)
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+type sqlTx struct {
+	q querier
+}
+
+func (t *sqlTx) tagsOf(id string) ([]string, error) {
+	rows, err := t.q.Query(`SELECT tag FROM tags WHERE person_id = ? ORDER BY position`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	tags := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, err
+		}
+		tags = append(tags, tag)
+	}
+	return tags, rows.Err()
+}
+
+// setTags replaces the tags of a person. A tag given twice is kept
+// at its first place, the positions stay without gaps.
+func (t *sqlTx) setTags(id string, tags []string) error {
+	if _, err := t.q.Exec(`DELETE FROM tags WHERE person_id = ?`, id); err != nil {
+		return err
+	}
+	seen := map[string]bool{}
+	for _, tag := range tags {
+		if seen[tag] {
+			continue
+		}
+		_, err := t.q.Exec(`INSERT INTO tags (person_id, tag, position) VALUES (?, ?, ?)`, id, tag, len(seen))
+		if err != nil {
+			return err
+		}
+		seen[tag] = true
+	}
+	return nil
+}
+
+// loadContacts fills the contact entries of people, selected by where
+func (t *sqlTx) loadContacts(people []person.Person, where string, args ...any) error {
+	rows, err := t.q.Query(`SELECT person_id, kind, label, value FROM contacts `+where+` ORDER BY person_id, kind, position`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	index := map[string]int{}
+	for i := range people {
+		index[people[i].ID] = i
+		for _, kind := range person.Kinds {
+			*people[i].Entries(kind) = []person.Entry{}
+		}
+	}
+	for rows.Next() {
+		var id, kind string
+		var e person.Entry
+		if err := rows.Scan(&id, &kind, &e.Label, &e.Value); err != nil {
+			return err
+		}
+		i, ok := index[id]
+		if !ok {
+			continue
+		}
+		if entries := people[i].Entries(kind); entries != nil {
+			*entries = append(*entries, e)
+		}
+	}
+	return rows.Err()
+}
+
+func (t *sqlTx) setContacts(p person.Person) error {
+	if _, err := t.q.Exec(`DELETE FROM contacts WHERE person_id = ?`, p.ID); err != nil {
+		return err
+	}
+	for _, kind := range person.Kinds {
+		for i, e := range *p.Entries(kind) {
+			_, err := t.q.Exec(`INSERT INTO contacts (person_id, kind, label, value, position) VALUES (?, ?, ?, ?, ?)`,
+				p.ID, kind, e.Label, e.Value, i)
+			if err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// loadCustom fills the custom field values of people, selected by where
+func (t *sqlTx) loadCustom(people []person.Person, where string, args ...any) error {
+	rows, err := t.q.Query(`SELECT person_id, field, value FROM custom_values `+where, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	index := map[string]int{}
+	for i := range people {
+		index[people[i].ID] = i
+		people[i].Custom = map[string]string{}
+	}
+	for rows.Next() {
+		var id, field, value string
+		if err := rows.Scan(&id, &field, &value); err != nil {
+			return err
+		}
+		if i, ok := index[id]; ok {
+			people[i].Custom[field] = value
+		}
+	}
+	return rows.Err()
+}
+
+func (t *sqlTx) setCustom(p person.Person) error {
+	if _, err := t.q.Exec(`DELETE FROM custom_values WHERE person_id = ?`, p.ID); err != nil {
+		return err
+	}
+	for field, value := range p.Custom {
+		_, err := t.q.Exec(`INSERT INTO custom_values (person_id, field, value) VALUES (?, ?, ?)`, p.ID, field, value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const personColumns = `id, name, notes, company, job_title, birthday, cadence, snoozed_until`
+
+func scanPerson(row interface{ Scan(...any) error }) (person.Person, error) {
+	var p person.Person
+	err := row.Scan(&p.ID, &p.Name, &p.Notes, &p.Company, &p.JobTitle, &p.Birthday, &p.Cadence, &p.SnoozedUntil)
+	return p, err
+}
+
+func (t *sqlTx) GetPerson(id string) (person.Person, error) {
+	p, err := scanPerson(t.q.QueryRow(`SELECT `+personColumns+` FROM people WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return p, fmt.Errorf("person %s: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return p, err
+	}
+	if p.Tags, err = t.tagsOf(id); err != nil {
+		return p, err
+	}
+	people := []person.Person{p}
+	if err := t.loadContacts(people, `WHERE person_id = ?`, id); err != nil {
+		return p, err
+	}
+	err = t.loadCustom(people, `WHERE person_id = ?`, id)
+	return people[0], err
+}
+
+func (t *sqlTx) ListPeople() ([]person.Person, error) {
+	rows, err := t.q.Query(`SELECT ` + personColumns + ` FROM people ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	people := []person.Person{}
+	for rows.Next() {
+		p, err := scanPerson(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		people = append(people, p)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Tags are loaded in one go instead of one query per person
+	tagRows, err := t.q.Query(`SELECT person_id, tag FROM tags ORDER BY person_id, position`)
+	if err != nil {
+		return nil, err
+	}
+	defer tagRows.Close()
+	tagsByPerson := map[string][]string{}
+	for tagRows.Next() {
+		var id, tag string
+		if err := tagRows.Scan(&id, &tag); err != nil {
+			return nil, err
+		}
+		tagsByPerson[id] = append(tagsByPerson[id], tag)
+	}
+	for i := range people {
+		people[i].Tags = tagsByPerson[people[i].ID]
+		if people[i].Tags == nil {
+			people[i].Tags = []string{}
+		}
+	}
+	if err := tagRows.Err(); err != nil {
+		return nil, err
+	}
+	if err := t.loadContacts(people, ``); err != nil {
+		return nil, err
+	}
+	return people, t.loadCustom(people, ``)
+}
+
+func (t *sqlTx) GetRelation(id string) (relation.Relation, error) {
+	r := relation.Relation{ID: id}
+	err := t.q.QueryRow(`SELECT from_id, to_id, strength, description FROM relations WHERE id = ?`, id).
+		Scan(&r.FromID, &r.ToID, &r.Strength, &r.Description)
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, fmt.Errorf("relation %s: %w", id, ErrNotFound)
+	}
+	return r, err
+}
+
+func (t *sqlTx) ListRelations() ([]relation.Relation, error) {
+	rows, err := t.q.Query(`SELECT id, from_id, to_id, strength, description FROM relations ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	rels := []relation.Relation{}
+	for rows.Next() {
+		var r relation.Relation
+		if err := rows.Scan(&r.ID, &r.FromID, &r.ToID, &r.Strength, &r.Description); err != nil {
+			return nil, err
+		}
+		rels = append(rels, r)
+	}
+	return rels, rows.Err()
+}
+
+func (t *sqlTx) CreatePerson(p person.Person) error {
+	_, err := t.q.Exec(`INSERT INTO people (`+personColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+		p.ID, p.Name, p.Notes, p.Company, p.JobTitle, p.Birthday, p.Cadence, p.SnoozedUntil)
+	if err != nil {
+		return fmt.Errorf("person %s: %w", p.ID, err)
+	}
+	if err := t.setTags(p.ID, p.Tags); err != nil {
+		return err
+	}
+	if err := t.setContacts(p); err != nil {
+		return err
+	}
+	return t.setCustom(p)
+}
+
+func (t *sqlTx) UpdatePerson(p person.Person) error {
+	res, err := t.q.Exec(`UPDATE people SET name = ?, notes = ?, company = ?, job_title = ?, birthday = ?,
+		cadence = ?, snoozed_until = ? WHERE id = ?`,
+		p.Name, p.Notes, p.Company, p.JobTitle, p.Birthday, p.Cadence, p.SnoozedUntil, p.ID)
+	if err := checkAffected(res, err, "person", p.ID); err != nil {
+		return err
+	}
+	if err := t.setTags(p.ID, p.Tags); err != nil {
+		return err
+	}
+	if err := t.setContacts(p); err != nil {
+		return err
+	}
+	return t.setCustom(p)
+}
+
+func (t *sqlTx) DeletePerson(id string) error {
+	res, err := t.q.Exec(`DELETE FROM people WHERE id = ?`, id)
+	if err := checkAffected(res, err, "person", id); err != nil {
+		return err
+	}
+	if _, err := t.q.Exec(`DELETE FROM tags WHERE person_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := t.q.Exec(`DELETE FROM contacts WHERE person_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := t.q.Exec(`DELETE FROM custom_values WHERE person_id = ?`, id); err != nil {
+		return err
+	}
+	if _, err := t.q.Exec(`DELETE FROM relations WHERE from_id = ? OR to_id = ?`, id, id); err != nil {
+		return err
+	}
+	if _, err := t.q.Exec(`DELETE FROM reminders WHERE person_id = ?`, id); err != nil {
+		return err
+	}
+	// Only the interactions of this person that nobody else took part in,
+	// interactions that had no participants before are not ours to drop
+	_, err = t.q.Exec(`DELETE FROM interactions WHERE id IN (SELECT interaction_id FROM participants WHERE person_id = ?)
+		AND NOT EXISTS (SELECT 1 FROM participants WHERE interaction_id = interactions.id AND person_id != ?)`, id, id)
+	if err != nil {
+		return err
+	}
+	_, err = t.q.Exec(`DELETE FROM participants WHERE person_id = ?`, id)
+	return err
+}
+
+func (t *sqlTx) CreateRelation(r relation.Relation) error {
+	_, err := t.q.Exec(`INSERT INTO relations (id, from_id, to_id, strength, description) VALUES (?, ?, ?, ?, ?)`,
+		r.ID, r.FromID, r.ToID, r.Strength, r.Description)
+	if err != nil {
+		return fmt.Errorf("relation %s: %w", r.ID, err)
+	}
+	return nil
+}
+
+func (t *sqlTx) UpdateRelation(r relation.Relation) error {
+	res, err := t.q.Exec(`UPDATE relations SET from_id = ?, to_id = ?, strength = ?, description = ? WHERE id = ?`,
+		r.FromID, r.ToID, r.Strength, r.Description, r.ID)
+	return checkAffected(res, err, "relation", r.ID)
+}
+
+func (t *sqlTx) DeleteRelation(id string) error {
+	res, err := t.q.Exec(`DELETE FROM relations WHERE id = ?`, id)
+	return checkAffected(res, err, "relation", id)
+}
+
+func scanField(row interface{ Scan(...any) error }) (db.FieldDef, error) {
+	var f db.FieldDef
+	var options string
+	if err := row.Scan(&f.Name, &f.Type, &options); err != nil {
+		return f, err
+	}
+	return f, json.Unmarshal([]byte(options), &f.Options)
+}
+
+func (t *sqlTx) GetField(name string) (db.FieldDef, error) {
+	f, err := scanField(t.q.QueryRow(`SELECT name, type, options FROM fields WHERE name = ?`, name))
+	if errors.Is(err, sql.ErrNoRows) {
+		return f, fmt.Errorf("field %s: %w", name, ErrNotFound)
+	}
+	return f, err
+}
+
+func (t *sqlTx) ListFields() ([]db.FieldDef, error) {
+	rows, err := t.q.Query(`SELECT name, type, options FROM fields ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	fields := []db.FieldDef{}
+	for rows.Next() {
+		f, err := scanField(rows)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, f)
+	}
+	return fields, rows.Err()
+}
+
+func (t *sqlTx) CreateField(f db.FieldDef) error {
+	options, err := json.Marshal(f.Options)
+	if err != nil {
+		return err
+	}
+	_, err = t.q.Exec(`INSERT INTO fields (name, type, options) VALUES (?, ?, ?)`, f.Name, f.Type, string(options))
+	if err != nil {
+		return fmt.Errorf("field %s: %w", f.Name, err)
+	}
+	return nil
+}
+
+func (t *sqlTx) UpdateField(f db.FieldDef) error {
+	options, err := json.Marshal(f.Options)
+	if err != nil {
+		return err
+	}
+	res, err := t.q.Exec(`UPDATE fields SET type = ?, options = ? WHERE name = ?`, f.Type, string(options), f.Name)
+	return checkAffected(res, err, "field", f.Name)
+}
+
+func (t *sqlTx) DeleteField(name string) error {
+	res, err := t.q.Exec(`DELETE FROM fields WHERE name = ?`, name)
+	return checkAffected(res, err, "field", name)
+}
+
+// loadParticipants fills the participants of list, selected by where
+func (t *sqlTx) loadParticipants(list []interaction.Interaction, where string, args ...any) error {
+	rows, err := t.q.Query(`SELECT interaction_id, person_id FROM participants `+where+` ORDER BY interaction_id, position`, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	index := map[string]int{}
+	for i := range list {
+		index[list[i].ID] = i
+		list[i].Participants = []string{}
+	}
+	for rows.Next() {
+		var id, personID string
+		if err := rows.Scan(&id, &personID); err != nil {
+			return err
+		}
+		if i, ok := index[id]; ok {
+			list[i].Participants = append(list[i].Participants, personID)
+		}
+	}
+	return rows.Err()
+}
+
+func (t *sqlTx) setParticipants(in interaction.Interaction) error {
+	if _, err := t.q.Exec(`DELETE FROM participants WHERE interaction_id = ?`, in.ID); err != nil {
+		return err
+	}
+	for i, id := range in.Participants {
+		_, err := t.q.Exec(`INSERT OR IGNORE INTO participants (interaction_id, person_id, position) VALUES (?, ?, ?)`, in.ID, id, i)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+const interactionColumns = `id, date, kind, summary, follow_up`
+
+func scanInteraction(row interface{ Scan(...any) error }) (interaction.Interaction, error) {
+	var in interaction.Interaction
+	err := row.Scan(&in.ID, &in.Date, &in.Kind, &in.Summary, &in.FollowUp)
+	return in, err
+}
+
+func (t *sqlTx) GetInteraction(id string) (interaction.Interaction, error) {
+	in, err := scanInteraction(t.q.QueryRow(`SELECT `+interactionColumns+` FROM interactions WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return in, fmt.Errorf("interaction %s: %w", id, ErrNotFound)
+	}
+	if err != nil {
+		return in, err
+	}
+	list := []interaction.Interaction{in}
+	err = t.loadParticipants(list, `WHERE interaction_id = ?`, id)
+	return list[0], err
+}
+
+func (t *sqlTx) ListInteractions() ([]interaction.Interaction, error) {
+	rows, err := t.q.Query(`SELECT ` + interactionColumns + ` FROM interactions ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	list := []interaction.Interaction{}
+	for rows.Next() {
+		in, err := scanInteraction(rows)
+		if err != nil {
+			rows.Close()
+			return nil, err
+		}
+		list = append(list, in)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, t.loadParticipants(list, ``)
+}
+
+func (t *sqlTx) CreateInteraction(in interaction.Interaction) error {
+	_, err := t.q.Exec(`INSERT INTO interactions (`+interactionColumns+`) VALUES (?, ?, ?, ?, ?)`,
+		in.ID, in.Date, in.Kind, in.Summary, in.FollowUp)
+	if err != nil {
+		return fmt.Errorf("interaction %s: %w", in.ID, err)
+	}
+	return t.setParticipants(in)
+}
+
+func (t *sqlTx) UpdateInteraction(in interaction.Interaction) error {
+	res, err := t.q.Exec(`UPDATE interactions SET date = ?, kind = ?, summary = ?, follow_up = ? WHERE id = ?`,
+		in.Date, in.Kind, in.Summary, in.FollowUp, in.ID)
+	if err := checkAffected(res, err, "interaction", in.ID); err != nil {
+		return err
+	}
+	return t.setParticipants(in)
+}
+
+func (t *sqlTx) DeleteInteraction(id string) error {
+	res, err := t.q.Exec(`DELETE FROM interactions WHERE id = ?`, id)
+	if err := checkAffected(res, err, "interaction", id); err != nil {
+		return err
+	}
+	_, err = t.q.Exec(`DELETE FROM participants WHERE interaction_id = ?`, id)
+	return err
+}
+
+const reminderColumns = `id, person_id, date, text, repeat, done, snoozed_until`
+
+func scanReminder(row interface{ Scan(...any) error }) (reminder.Reminder, error) {
+	var r reminder.Reminder
+	err := row.Scan(&r.ID, &r.PersonID, &r.Date, &r.Text, &r.Repeat, &r.Done, &r.SnoozedUntil)
+	return r, err
+}
+
+func (t *sqlTx) GetReminder(id string) (reminder.Reminder, error) {
+	r, err := scanReminder(t.q.QueryRow(`SELECT `+reminderColumns+` FROM reminders WHERE id = ?`, id))
+	if errors.Is(err, sql.ErrNoRows) {
+		return r, fmt.Errorf("reminder %s: %w", id, ErrNotFound)
+	}
+	return r, err
+}
+
+func (t *sqlTx) ListReminders() ([]reminder.Reminder, error) {
+	rows, err := t.q.Query(`SELECT ` + reminderColumns + ` FROM reminders ORDER BY rowid`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	list := []reminder.Reminder{}
+	for rows.Next() {
+		r, err := scanReminder(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, r)
+	}
+	return list, rows.Err()
+}
+
+func (t *sqlTx) CreateReminder(r reminder.Reminder) error {
+	_, err := t.q.Exec(`INSERT INTO reminders (`+reminderColumns+`) VALUES (?, ?, ?, ?, ?, ?, ?)`,
+		r.ID, r.PersonID, r.Date, r.Text, r.Repeat, r.Done, r.SnoozedUntil)
+	if err != nil {
+		return fmt.Errorf("reminder %s: %w", r.ID, err)
+	}
+	return nil
+}
+
+func (t *sqlTx) UpdateReminder(r reminder.Reminder) error {
+	res, err := t.q.Exec(`UPDATE reminders SET person_id = ?, date = ?, text = ?, repeat = ?, done = ?, snoozed_until = ? WHERE id = ?`,
+		r.PersonID, r.Date, r.Text, r.Repeat, r.Done, r.SnoozedUntil, r.ID)
+	return checkAffected(res, err, "reminder", r.ID)
+}
+
+func (t *sqlTx) DeleteReminder(id string) error {
+	res, err := t.q.Exec(`DELETE FROM reminders WHERE id = ?`, id)
+	return checkAffected(res, err, "reminder", id)
+}
+
+// The undo log is only ever read and written as a whole, so it lives in meta as json
+func (t *sqlTx) UndoLog() (db.UndoLog, error) {
+	var log db.UndoLog
+	var raw string
+	err := t.q.QueryRow(`SELECT value FROM meta WHERE key = 'undo'`).Scan(&raw)
+	if errors.Is(err, sql.ErrNoRows) {
+		return log, nil
+	}
+	if err != nil {
+		return log, err
+	}
+	return log, json.Unmarshal([]byte(raw), &log)
+}
+
+func (t *sqlTx) SaveUndoLog(log db.UndoLog) error {
+	raw, err := json.Marshal(log)
+	if err != nil {
+		return err
+	}
+	_, err = t.q.Exec(`INSERT INTO meta (key, value) VALUES ('undo', ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value`, string(raw))
+	return err
+}
+
+func (t *sqlTx) AppendEvents(events []db.Event) error {
+	for _, e := range events {
+		related, err := json.Marshal(e.Related)
+		if err != nil {
+			return err
+		}
+		_, err = t.q.Exec(`INSERT INTO events (time, entity, entity_id, label, related, action, field, old_value, new_value)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			e.Time.Format(time.RFC3339Nano), e.Entity, e.EntityID, e.Label, string(related), e.Action, e.Field, e.Old, e.New)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (t *sqlTx) ListEvents(personID string) ([]db.Event, error) {
+	query := `SELECT time, entity, entity_id, label, related, action, field, old_value, new_value FROM events`
+	args := []any{}
+	if personID != "" {
+		query += ` WHERE entity_id = ? OR EXISTS (SELECT 1 FROM json_each(events.related) WHERE value = ?)`
+		args = append(args, personID, personID)
+	}
+	rows, err := t.q.Query(query+` ORDER BY seq`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	events := []db.Event{}
+	for rows.Next() {
+		var e db.Event
+		var ts, related string
+		if err := rows.Scan(&ts, &e.Entity, &e.EntityID, &e.Label, &related, &e.Action, &e.Field, &e.Old, &e.New); err != nil {
+			return nil, err
+		}
+		if e.Time, err = time.Parse(time.RFC3339Nano, ts); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal([]byte(related), &e.Related); err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// checkAffected turns an update that did not touch any row into ErrNotFound
+func checkAffected(res sql.Result, err error, kind, id string) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return fmt.Errorf("%s %s: %w", kind, id, ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,191 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/migration"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/reminder"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Reader is the read only part of a store
+type Reader interface {
+	GetPerson(id string) (person.Person, error)
+	ListPeople() ([]person.Person, error)
+	GetRelation(id string) (relation.Relation, error)
+	ListRelations() ([]relation.Relation, error)
+	// Custom fields are looked up by name, ignoring case
+	GetField(name string) (db.FieldDef, error)
+	ListFields() ([]db.FieldDef, error)
+	GetInteraction(id string) (interaction.Interaction, error)
+	ListInteractions() ([]interaction.Interaction, error)
+	GetReminder(id string) (reminder.Reminder, error)
+	ListReminders() ([]reminder.Reminder, error)
+}
+
+// Tx is everything that can be done inside of a transaction.
+// Either all writes of a transaction are applied or none of them.
+type Tx interface {
+	Reader
+
+	CreatePerson(p person.Person) error
+	UpdatePerson(p person.Person) error
+	// DeletePerson also removes every relation touching the person and their
+	// reminders, and takes them out of their interactions. Interactions nobody
+	// is left in are removed.
+	DeletePerson(id string) error
+
+	CreateRelation(r relation.Relation) error
+	UpdateRelation(r relation.Relation) error
+	DeleteRelation(id string) error
+
+	CreateField(f db.FieldDef) error
+	UpdateField(f db.FieldDef) error
+	// DeleteField keeps the values people have for the field
+	DeleteField(name string) error
+
+	CreateInteraction(i interaction.Interaction) error
+	UpdateInteraction(i interaction.Interaction) error
+	DeleteInteraction(id string) error
+
+	CreateReminder(r reminder.Reminder) error
+	UpdateReminder(r reminder.Reminder) error
+	DeleteReminder(id string) error
+
+	UndoLog() (db.UndoLog, error)
+	SaveUndoLog(log db.UndoLog) error
+
+	// The history is append only, events are never changed or removed
+	AppendEvents(events []db.Event) error
+	// ListEvents returns the history of a person and their relations, oldest first.
+	// An empty personID returns everything.
+	ListEvents(personID string) ([]db.Event, error)
+}
+
+// Store is the storage backend the TUI talks to.
+// Calling a write method directly on the store runs it in its own transaction.
+type Store interface {
+	Tx
+
+	// Update runs fn inside of a transaction. If fn returns an error
+	// nothing is written.
+	Update(fn func(tx Tx) error) error
+	// Snapshot returns a copy of the whole database
+	Snapshot() (db.Database, error)
+	Close() error
+}
+
+// Reloader is implemented by stores that can pick up changes
+// someone else made to the database file
+type Reloader interface {
+	// Changed reports if the database on disk differs from what the store has seen last
+	Changed() (bool, error)
+	// Reload replaces the in memory state with what is on disk
+	Reload() error
+}
+
+// IsSQLite reports if dbPath should be opened with the sqlite backend.
+// The backend is picked by the file extension, everything else is json.
+func IsSQLite(dbPath string) bool {
+	switch strings.ToLower(filepath.Ext(dbPath)) {
+	case ".db", ".sqlite", ".sqlite3":
+		return true
+	}
+	return false
+}
+
+// Open opens the backend matching the extension of dbPath
+func Open(dbPath string) (Store, error) {
+	if IsSQLite(dbPath) {
+		return OpenSQLite(dbPath)
+	}
+	return OpenJSON(dbPath)
+}
+
+// ImportJSON copies a json database into a sqlite store. Older databases are
+// migrated on the way, the json file itself is left as it is.
+func ImportJSON(jsonPath string, dst *SQLiteStore) error {
+	database, err := readMigrated(jsonPath)
+	if err != nil {
+		return err
+	}
+	return dst.Import(database)
+}
+
+// readMigrated reads the json database at jsonPath and brings it up to date
+// in memory, the file itself is left as it is
+func readMigrated(jsonPath string) (db.Database, error) {
+	p, err := migration.Prepare(jsonPath)
+	if err != nil {
+		return db.Database{}, err
+	}
+	if p == nil {
+		return db.Database{}, fmt.Errorf("%s: %w", jsonPath, os.ErrNotExist)
+	}
+	raw, err := json.Marshal(p.After)
+	if err != nil {
+		return db.Database{}, fmt.Errorf("encoding migrated db: %w", err)
+	}
+	var database db.Database
+	if err := json.Unmarshal(raw, &database); err != nil {
+		return db.Database{}, db.DescribeJSONError(jsonPath, raw, err)
+	}
+	fillRelationIDs(database)
+	return database, nil
+}
+
+// ImportJSONFile imports the json database at jsonPath into the sqlite database
+// at dbPath. A new database is built next to dbPath and only moved into place
+// once the import went through, a failed import leaves no empty database behind.
+func ImportJSONFile(jsonPath, dbPath string) error {
+	if _, err := os.Stat(dbPath); err == nil {
+		// Import replaces everything in one transaction, a failure changes nothing
+		st, err := OpenSQLite(dbPath)
+		if err != nil {
+			return err
+		}
+		defer st.Close()
+		return ImportJSON(jsonPath, st)
+	}
+
+	tmp := dbPath + ".import"
+	// Leftovers of an import that was killed
+	if err := removeSQLite(tmp); err != nil {
+		return err
+	}
+	st, err := OpenSQLite(tmp)
+	if err != nil {
+		return err
+	}
+	err = ImportJSON(jsonPath, st)
+	if closeErr := st.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		if removeErr := removeSQLite(tmp); removeErr != nil {
+			return errors.Join(err, removeErr)
+		}
+		return err
+	}
+	return os.Rename(tmp, dbPath)
+}
+
+// removeSQLite deletes a sqlite database together with its journals
+func removeSQLite(path string) error {
+	for _, suffix := range []string{"", "-wal", "-shm"} {
+		if err := os.Remove(path + suffix); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,433 @@
+package store
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/migration"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/reminder"
+)
+
+// Every backend has to behave the same, so the tests below run against all of them
+
+type backend struct {
+	name string
+	open func(t *testing.T, dir string) Store
+}
+
+var backends = []backend{
+	{"memory", func(t *testing.T, dir string) Store {
+		database, err := readData(filepath.Join(dir, "missing.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return NewMemory(database)
+	}},
+	{"json", func(t *testing.T, dir string) Store {
+		s, err := OpenJSON(filepath.Join(dir, "data.json"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}},
+	{"sqlite", func(t *testing.T, dir string) Store {
+		s, err := OpenSQLite(filepath.Join(dir, "data.db"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return s
+	}},
+}
+
+func forEachBackend(t *testing.T, test func(t *testing.T, open func() Store)) {
+	for _, b := range backends {
+		t.Run(b.name, func(t *testing.T) {
+			dir := t.TempDir()
+			test(t, func() Store {
+				s := b.open(t, dir)
+				t.Cleanup(func() { s.Close() })
+				return s
+			})
+		})
+	}
+}
+
+func ada() person.Person {
+	return person.Person{
+		ID:           "p1",
+		Name:         "Ada",
+		Notes:        "Met at the conference",
+		Tags:         []string{"math", "work"},
+		Company:      "Analytical Engines",
+		JobTitle:     "Programmer",
+		Birthday:     "1815-12-10",
+		Emails:       []person.Entry{{Label: "work", Value: "ada@example.com"}},
+		Phones:       []person.Entry{{Label: "mobile", Value: "+44 1"}},
+		Addresses:    []person.Entry{{Label: "home", Value: "London"}},
+		URLs:         []person.Entry{{Label: "site", Value: "ada.example.com"}},
+		Custom:       map[string]string{"timezone": "GMT"},
+		Cadence:      30,
+		SnoozedUntil: "2024-07-01",
+	}
+}
+
+func grace() person.Person {
+	return person.Person{
+		ID:        "p2",
+		Name:      "Grace",
+		Tags:      []string{},
+		Emails:    []person.Entry{},
+		Phones:    []person.Entry{},
+		Addresses: []person.Entry{},
+		URLs:      []person.Entry{},
+		Custom:    map[string]string{},
+	}
+}
+
+func mustDo(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPersonCRUD(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() Store) {
+		s := open()
+		mustDo(t, s.CreatePerson(ada()))
+		mustDo(t, s.CreatePerson(grace()))
+
+		got, err := s.GetPerson("p1")
+		mustDo(t, err)
+		if !reflect.DeepEqual(got, ada()) {
+			t.Fatalf("got %+v, want %+v", got, ada())
+		}
+
+		changed := ada()
+		changed.Name = "Ada Lovelace"
+		changed.Tags = []string{"work"}
+		changed.Custom = map[string]string{}
+		mustDo(t, s.UpdatePerson(changed))
+		if got, _ := s.GetPerson("p1"); !reflect.DeepEqual(got, changed) {
+			t.Fatalf("after update got %+v, want %+v", got, changed)
+		}
+
+		mustDo(t, s.DeletePerson("p2"))
+		if _, err := s.GetPerson("p2"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get after delete: got %v, want ErrNotFound", err)
+		}
+		people, err := s.ListPeople()
+		mustDo(t, err)
+		if len(people) != 1 || people[0].ID != "p1" {
+			t.Fatalf("got %v, want only p1", people)
+		}
+
+		missing := grace()
+		missing.ID = "nobody"
+		if err := s.UpdatePerson(missing); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("update of a missing person: got %v, want ErrNotFound", err)
+		}
+		if err := s.DeletePerson("nobody"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("delete of a missing person: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestDeletePersonTakesTheirData(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() Store) {
+		s := open()
+		carol := grace()
+		carol.ID, carol.Name = "p3", "Carol"
+		mustDo(t, s.Update(func(tx Tx) error {
+			for _, p := range []person.Person{ada(), grace(), carol} {
+				if err := tx.CreatePerson(p); err != nil {
+					return err
+				}
+			}
+			for _, r := range []relation.Relation{
+				{ID: "r1", FromID: "p1", ToID: "p2", Strength: 4},
+				{ID: "r2", FromID: "p2", ToID: "p3", Strength: 2},
+			} {
+				if err := tx.CreateRelation(r); err != nil {
+					return err
+				}
+			}
+			for _, in := range []interaction.Interaction{
+				{ID: "i1", Date: "2024-05-01", Kind: "call", Participants: []string{"p1", "p2"}},
+				{ID: "i2", Date: "2024-05-02", Kind: "call", Participants: []string{"p1"}},
+				// Broken before, it has nothing to do with p1
+				{ID: "i3", Date: "2024-05-03", Kind: "call", Participants: []string{}},
+			} {
+				if err := tx.CreateInteraction(in); err != nil {
+					return err
+				}
+			}
+			return tx.CreateReminder(reminder.Reminder{ID: "m1", PersonID: "p1", Date: "2024-12-10", Text: "Card"})
+		}))
+
+		mustDo(t, s.DeletePerson("p1"))
+
+		rels, err := s.ListRelations()
+		mustDo(t, err)
+		if len(rels) != 1 || rels[0].ID != "r2" {
+			t.Fatalf("relations: got %v, want only r2", rels)
+		}
+		interactions, err := s.ListInteractions()
+		mustDo(t, err)
+		if len(interactions) != 2 || !slices.Equal(interactions[0].Participants, []string{"p2"}) || interactions[1].ID != "i3" {
+			t.Fatalf("interactions: got %v, want i1 with only p2 left and i3", interactions)
+		}
+		reminders, err := s.ListReminders()
+		mustDo(t, err)
+		if len(reminders) != 0 {
+			t.Fatalf("reminders: got %v, want none", reminders)
+		}
+	})
+}
+
+func TestSQLiteKeepsDuplicateTagsOnce(t *testing.T) {
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "data.db"))
+	mustDo(t, err)
+	defer s.Close()
+	p := ada()
+	p.Tags = []string{"work", "math", "work", "chess"}
+	mustDo(t, s.Import(db.Database{People: []person.Person{p}}))
+
+	got, err := s.GetPerson("p1")
+	mustDo(t, err)
+	if want := []string{"work", "math", "chess"}; !slices.Equal(got.Tags, want) {
+		t.Fatalf("got %v, want %v", got.Tags, want)
+	}
+	var last int
+	mustDo(t, s.db.QueryRow(`SELECT MAX(position) FROM tags WHERE person_id = 'p1'`).Scan(&last))
+	if last != 2 {
+		t.Fatalf("the last tag is at position %d, want 2", last)
+	}
+}
+
+func TestRelationCRUD(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() Store) {
+		s := open()
+		mustDo(t, s.CreatePerson(ada()))
+		mustDo(t, s.CreatePerson(grace()))
+		r := relation.Relation{ID: "r1", FromID: "p1", ToID: "p2", Strength: 4, Description: "Colleagues"}
+		mustDo(t, s.CreateRelation(r))
+
+		r.Strength = 8
+		mustDo(t, s.UpdateRelation(r))
+		got, err := s.GetRelation("r1")
+		mustDo(t, err)
+		if got != r {
+			t.Fatalf("got %+v, want %+v", got, r)
+		}
+
+		mustDo(t, s.DeleteRelation("r1"))
+		if _, err := s.GetRelation("r1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get after delete: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestFieldsIgnoreCase(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() Store) {
+		s := open()
+		f := db.FieldDef{Name: "Level", Type: db.FieldEnum, Options: []string{"low", "high"}}
+		mustDo(t, s.CreateField(f))
+
+		got, err := s.GetField("level")
+		mustDo(t, err)
+		if !reflect.DeepEqual(got, f) {
+			t.Fatalf("got %+v, want %+v", got, f)
+		}
+		mustDo(t, s.DeleteField("LEVEL"))
+		if _, err := s.GetField("Level"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("get after delete: got %v, want ErrNotFound", err)
+		}
+	})
+}
+
+func TestFailedUpdateWritesNothing(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() Store) {
+		s := open()
+		failed := errors.New("failed")
+		err := s.Update(func(tx Tx) error {
+			if err := tx.CreatePerson(ada()); err != nil {
+				return err
+			}
+			return failed
+		})
+		if !errors.Is(err, failed) {
+			t.Fatalf("got %v, want the error of fn", err)
+		}
+		if _, err := s.GetPerson("p1"); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("got %v, the person should not have been created", err)
+		}
+	})
+}
+
+func TestReopenKeepsData(t *testing.T) {
+	forEachBackend(t, func(t *testing.T, open func() Store) {
+		s := open()
+		if _, ok := s.(*MemoryStore); ok {
+			t.Skip("nothing is written to disk")
+		}
+		mustDo(t, s.CreatePerson(ada()))
+		mustDo(t, s.CreateReminder(reminder.Reminder{ID: "m1", PersonID: "p1", Date: "2024-12-10", Text: "Card", Repeat: reminder.RepeatYearly}))
+		mustDo(t, s.Close())
+
+		s = open()
+		got, err := s.GetPerson("p1")
+		mustDo(t, err)
+		if !reflect.DeepEqual(got, ada()) {
+			t.Fatalf("got %+v, want %+v", got, ada())
+		}
+		if _, err := s.GetReminder("m1"); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
+
+func TestImportJSONMigratesACopy(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "old.json")
+	old := []byte(`{"version": "1.0.0", "people": [{"id": "p1", "name": "Ada", "notes": "", "tags": []}], "relations": []}`)
+	mustDo(t, os.WriteFile(jsonPath, old, 0644))
+
+	dbPath := filepath.Join(dir, "data.db")
+	s, err := OpenSQLite(dbPath)
+	mustDo(t, err)
+	mustDo(t, ImportJSON(jsonPath, s))
+	mustDo(t, s.Close())
+
+	// The schema stays at the current version, so the file opens again
+	s, err = OpenSQLite(dbPath)
+	mustDo(t, err)
+	defer s.Close()
+	database, err := s.Snapshot()
+	mustDo(t, err)
+	if database.Version != config.DB_FORMAT_VERSION || len(database.People) != 1 {
+		t.Fatalf("got version %s with %d people", database.Version, len(database.People))
+	}
+	if content, _ := os.ReadFile(jsonPath); !bytes.Equal(content, old) {
+		t.Fatalf("the json file was changed:\n%s", content)
+	}
+}
+
+func TestImportRejectsOldData(t *testing.T) {
+	s, err := OpenSQLite(filepath.Join(t.TempDir(), "data.db"))
+	mustDo(t, err)
+	defer s.Close()
+	if err := s.Import(db.Database{Version: "1.0.0"}); err == nil {
+		t.Fatal("importing 1.0.0 data should fail")
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "data.db")
+	s, err := OpenSQLite(dbPath)
+	mustDo(t, err)
+	mustDo(t, s.CreatePerson(ada()))
+	mustDo(t, s.Close())
+
+	ro, err := OpenReadOnly(dbPath)
+	mustDo(t, err)
+	defer ro.Close()
+	if _, err := ro.GetPerson("p1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := ro.CreatePerson(grace()); !errors.Is(err, ErrReadOnly) {
+		t.Fatalf("got %v, want ErrReadOnly", err)
+	}
+	if _, err := OpenReadOnly(filepath.Join(t.TempDir(), "missing.db")); err == nil {
+		t.Fatal("opening a missing file read only should fail")
+	}
+}
+
+func TestNewerJSONIsRefused(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "data.json")
+	s, err := OpenJSON(dbPath)
+	mustDo(t, err)
+	mustDo(t, s.CreatePerson(ada()))
+
+	newer := []byte(`{"version": "99.0.0", "people": [], "relations": []}`)
+	mustDo(t, os.WriteFile(dbPath, newer, 0644))
+	if err := s.Reload(); !errors.Is(err, migration.ErrNewerVersion) {
+		t.Fatalf("reload: got %v, want ErrNewerVersion", err)
+	}
+	if _, err := s.GetPerson("p1"); err != nil {
+		t.Fatalf("the refused reload dropped what was loaded: %v", err)
+	}
+	if _, err := OpenReadOnly(dbPath); !errors.Is(err, migration.ErrNewerVersion) {
+		t.Fatalf("read only: got %v, want ErrNewerVersion", err)
+	}
+}
+
+func TestOpenReadOnlyMigratesOldJSON(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "data.json")
+	old := []byte(`{"version": "1.0.0", "people": [{"id": "p1", "name": "Ada", "notes": "", "tags": []}], "relations": []}`)
+	mustDo(t, os.WriteFile(dbPath, old, 0644))
+
+	ro, err := OpenReadOnly(dbPath)
+	mustDo(t, err)
+	defer ro.Close()
+	database, err := ro.Snapshot()
+	mustDo(t, err)
+	if database.Version != config.DB_FORMAT_VERSION || len(database.People) != 1 {
+		t.Fatalf("got version %s with %d people", database.Version, len(database.People))
+	}
+	if content, _ := os.ReadFile(dbPath); !bytes.Equal(content, old) {
+		t.Fatalf("the file was changed:\n%s", content)
+	}
+}
+
+func TestImportJSONFile(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "data.json")
+	mustDo(t, os.WriteFile(jsonPath, []byte(`{"version": "1.0.0", "people": [{"id": "p1", "name": "Ada", "notes": "", "tags": []}], "relations": []}`), 0644))
+	dbPath := filepath.Join(dir, "data.db")
+	mustDo(t, ImportJSONFile(jsonPath, dbPath))
+
+	entries, err := os.ReadDir(dir)
+	mustDo(t, err)
+	for _, e := range entries {
+		if e.Name() != "data.json" && e.Name() != "data.db" {
+			t.Errorf("left %s behind", e.Name())
+		}
+	}
+	s, err := OpenSQLite(dbPath)
+	mustDo(t, err)
+	defer s.Close()
+	if _, err := s.GetPerson("p1"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFailedImportJSONFileLeavesNoDatabase(t *testing.T) {
+	dir := t.TempDir()
+	jsonPath := filepath.Join(dir, "data.json")
+	mustDo(t, os.WriteFile(jsonPath, []byte(`{"version": "9.0.0", "people": []}`), 0644))
+	dbPath := filepath.Join(dir, "data.db")
+	if err := ImportJSONFile(jsonPath, dbPath); err == nil {
+		t.Fatal("importing a newer database should fail")
+	}
+
+	entries, err := os.ReadDir(dir)
+	mustDo(t, err)
+	if len(entries) != 1 {
+		names := []string{}
+		for _, e := range entries {
+			names = append(names, e.Name())
+		}
+		t.Fatalf("got %v, want only data.json", names)
+	}
+}
@@ -0,0 +1,551 @@
+package undo
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/history"
+	"github.com/N3moAhead/connect3/internal/interaction"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/reminder"
+	"github.com/N3moAhead/connect3/internal/store"
+)
+
+var (
+	ErrNothingToUndo = errors.New("nothing to undo")
+	ErrNothingToRedo = errors.New("nothing to redo")
+	// ErrStale is returned for an operation on something that was changed
+	// or deleted outside of this session, the operation is dropped
+	ErrStale = errors.New("changed outside of this session")
+)
+
+// Recorder is a store.Tx that remembers every change made through it,
+// including the relations, interactions and reminders a person delete takes with it
+type Recorder struct {
+	store.Tx
+	Changes []db.Change
+}
+
+func Record(tx store.Tx) *Recorder {
+	return &Recorder{Tx: tx}
+}
+
+func (r *Recorder) add(entity, id string, before, after any) error {
+	c := db.Change{Entity: entity, ID: id}
+	var err error
+	if before != nil {
+		if c.Before, err = json.Marshal(before); err != nil {
+			return err
+		}
+	}
+	if after != nil {
+		if c.After, err = json.Marshal(after); err != nil {
+			return err
+		}
+	}
+	r.Changes = append(r.Changes, c)
+	return nil
+}
+
+func (r *Recorder) CreatePerson(p person.Person) error {
+	if err := r.Tx.CreatePerson(p); err != nil {
+		return err
+	}
+	return r.add(db.EntityPerson, p.ID, nil, p)
+}
+
+func (r *Recorder) UpdatePerson(p person.Person) error {
+	before, err := r.Tx.GetPerson(p.ID)
+	if err != nil {
+		return err
+	}
+	if err := r.Tx.UpdatePerson(p); err != nil {
+		return err
+	}
+	return r.add(db.EntityPerson, p.ID, before, p)
+}
+
+func (r *Recorder) DeletePerson(id string) error {
+	before, err := r.Tx.GetPerson(id)
+	if err != nil {
+		return err
+	}
+	rels, err := r.Tx.ListRelations()
+	if err != nil {
+		return err
+	}
+	interactions, err := r.Tx.ListInteractions()
+	if err != nil {
+		return err
+	}
+	reminders, err := r.Tx.ListReminders()
+	if err != nil {
+		return err
+	}
+	if err := r.Tx.DeletePerson(id); err != nil {
+		return err
+	}
+	// The store removes the relations on its own, we only have to remember them
+	for _, rel := range rels {
+		if rel.FromID == id || rel.ToID == id {
+			if err := r.add(db.EntityRelation, rel.ID, rel, nil); err != nil {
+				return err
+			}
+		}
+	}
+	// Same for the interactions, which either lose a participant or are gone
+	for _, in := range interactions {
+		if !in.Involves(id) {
+			continue
+		}
+		after, err := r.Tx.GetInteraction(in.ID)
+		if errors.Is(err, store.ErrNotFound) {
+			err = r.add(db.EntityInteraction, in.ID, in, nil)
+		} else if err == nil {
+			err = r.add(db.EntityInteraction, in.ID, in, after)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	for _, rem := range reminders {
+		if rem.PersonID == id {
+			if err := r.add(db.EntityReminder, rem.ID, rem, nil); err != nil {
+				return err
+			}
+		}
+	}
+	return r.add(db.EntityPerson, id, before, nil)
+}
+
+func (r *Recorder) CreateRelation(rel relation.Relation) error {
+	if err := r.Tx.CreateRelation(rel); err != nil {
+		return err
+	}
+	return r.add(db.EntityRelation, rel.ID, nil, rel)
+}
+
+func (r *Recorder) UpdateRelation(rel relation.Relation) error {
+	before, err := r.Tx.GetRelation(rel.ID)
+	if err != nil {
+		return err
+	}
+	if err := r.Tx.UpdateRelation(rel); err != nil {
+		return err
+	}
+	return r.add(db.EntityRelation, rel.ID, before, rel)
+}
+
+func (r *Recorder) DeleteRelation(id string) error {
+	before, err := r.Tx.GetRelation(id)
+	if err != nil {
+		return err
+	}
+	if err := r.Tx.DeleteRelation(id); err != nil {
+		return err
+	}
+	return r.add(db.EntityRelation, id, before, nil)
+}
+
+func (r *Recorder) CreateField(f db.FieldDef) error {
+	if err := r.Tx.CreateField(f); err != nil {
+		return err
+	}
+	return r.add(db.EntityField, f.Name, nil, f)
+}
+
+func (r *Recorder) UpdateField(f db.FieldDef) error {
+	before, err := r.Tx.GetField(f.Name)
+	if err != nil {
+		return err
+	}
+	if err := r.Tx.UpdateField(f); err != nil {
+		return err
+	}
+	return r.add(db.EntityField, before.Name, before, f)
+}
+
+func (r *Recorder) DeleteField(name string) error {
+	before, err := r.Tx.GetField(name)
+	if err != nil {
+		return err
+	}
+	if err := r.Tx.DeleteField(name); err != nil {
+		return err
+	}
+	return r.add(db.EntityField, before.Name, before, nil)
+}
+
+func (r *Recorder) CreateInteraction(in interaction.Interaction) error {
+	if err := r.Tx.CreateInteraction(in); err != nil {
+		return err
+	}
+	return r.add(db.EntityInteraction, in.ID, nil, in)
+}
+
+func (r *Recorder) UpdateInteraction(in interaction.Interaction) error {
+	before, err := r.Tx.GetInteraction(in.ID)
+	if err != nil {
+		return err
+	}
+	if err := r.Tx.UpdateInteraction(in); err != nil {
+		return err
+	}
+	return r.add(db.EntityInteraction, in.ID, before, in)
+}
+
+func (r *Recorder) DeleteInteraction(id string) error {
+	before, err := r.Tx.GetInteraction(id)
+	if err != nil {
+		return err
+	}
+	if err := r.Tx.DeleteInteraction(id); err != nil {
+		return err
+	}
+	return r.add(db.EntityInteraction, id, before, nil)
+}
+
+func (r *Recorder) CreateReminder(rem reminder.Reminder) error {
+	if err := r.Tx.CreateReminder(rem); err != nil {
+		return err
+	}
+	return r.add(db.EntityReminder, rem.ID, nil, rem)
+}
+
+func (r *Recorder) UpdateReminder(rem reminder.Reminder) error {
+	before, err := r.Tx.GetReminder(rem.ID)
+	if err != nil {
+		return err
+	}
+	if err := r.Tx.UpdateReminder(rem); err != nil {
+		return err
+	}
+	return r.add(db.EntityReminder, rem.ID, before, rem)
+}
+
+func (r *Recorder) DeleteReminder(id string) error {
+	before, err := r.Tx.GetReminder(id)
+	if err != nil {
+		return err
+	}
+	if err := r.Tx.DeleteReminder(id); err != nil {
+		return err
+	}
+	return r.add(db.EntityReminder, id, before, nil)
+}
+
+// Do runs fn in a transaction and puts everything it changed
+// onto the undo log as one operation called name.
+// The changes also end up in the history.
+func Do(st store.Store, name string, fn func(tx store.Tx) error) error {
+	return st.Update(func(tx store.Tx) error {
+		rec := Record(tx)
+		if err := fn(rec); err != nil {
+			return err
+		}
+		if len(rec.Changes) == 0 {
+			return nil
+		}
+		now := time.Now()
+		if err := appendHistory(tx, rec.Changes, now); err != nil {
+			return err
+		}
+		log, err := tx.UndoLog()
+		if err != nil {
+			return err
+		}
+		log.Undo = append(log.Undo, db.Operation{Name: name, Time: now, Changes: rec.Changes})
+		if len(log.Undo) > config.UNDO_LIMIT {
+			log.Undo = log.Undo[len(log.Undo)-config.UNDO_LIMIT:]
+		}
+		// A new change makes the redo history meaningless
+		log.Redo = nil
+		return tx.SaveUndoLog(log)
+	})
+}
+
+// Undo reverts the newest operation and returns it
+func Undo(st store.Store) (db.Operation, error) {
+	var op db.Operation
+	err := st.Update(func(tx store.Tx) error {
+		log, err := tx.UndoLog()
+		if err != nil {
+			return err
+		}
+		if len(log.Undo) == 0 {
+			return ErrNothingToUndo
+		}
+		op = log.Undo[len(log.Undo)-1]
+		// Backwards, so a deleted person is back before their relations
+		reverted := make([]db.Change, 0, len(op.Changes))
+		for i := len(op.Changes) - 1; i >= 0; i-- {
+			c := op.Changes[i]
+			if err := expect(tx, c.Entity, c.ID, c.After); err != nil {
+				return err
+			}
+			if err := apply(tx, c.Entity, c.ID, c.After, c.Before); err != nil {
+				return fmt.Errorf("undo %s: %w", op.Name, err)
+			}
+			reverted = append(reverted, db.Change{Entity: c.Entity, ID: c.ID, Before: c.After, After: c.Before})
+		}
+		if err := appendHistory(tx, reverted, time.Now()); err != nil {
+			return err
+		}
+		log.Undo = log.Undo[:len(log.Undo)-1]
+		log.Redo = append(log.Redo, op)
+		return tx.SaveUndoLog(log)
+	})
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, ErrStale) {
+		return op, dropStale(st, op, false)
+	}
+	return op, err
+}
+
+// Redo applies the newest undone operation again and returns it
+func Redo(st store.Store) (db.Operation, error) {
+	var op db.Operation
+	err := st.Update(func(tx store.Tx) error {
+		log, err := tx.UndoLog()
+		if err != nil {
+			return err
+		}
+		if len(log.Redo) == 0 {
+			return ErrNothingToRedo
+		}
+		op = log.Redo[len(log.Redo)-1]
+		for _, c := range op.Changes {
+			if err := expect(tx, c.Entity, c.ID, c.Before); err != nil {
+				return err
+			}
+			if err := apply(tx, c.Entity, c.ID, c.Before, c.After); err != nil {
+				return fmt.Errorf("redo %s: %w", op.Name, err)
+			}
+		}
+		if err := appendHistory(tx, op.Changes, time.Now()); err != nil {
+			return err
+		}
+		log.Redo = log.Redo[:len(log.Redo)-1]
+		log.Undo = append(log.Undo, op)
+		return tx.SaveUndoLog(log)
+	})
+	if errors.Is(err, store.ErrNotFound) || errors.Is(err, ErrStale) {
+		return op, dropStale(st, op, true)
+	}
+	return op, err
+}
+
+// dropStale takes op off the top of the undo or redo stack after it could not be
+// applied, it would block everything below it for good. It returns ErrStale.
+func dropStale(st store.Store, op db.Operation, redo bool) error {
+	name := "undo"
+	if redo {
+		name = "redo"
+	}
+	err := st.Update(func(tx store.Tx) error {
+		log, err := tx.UndoLog()
+		if err != nil {
+			return err
+		}
+		stack := &log.Undo
+		if redo {
+			stack = &log.Redo
+		}
+		n := len(*stack)
+		if n == 0 || (*stack)[n-1].Name != op.Name || !(*stack)[n-1].Time.Equal(op.Time) {
+			// Somebody else got to it first
+			return nil
+		}
+		*stack = (*stack)[:n-1]
+		return tx.SaveUndoLog(log)
+	})
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("dropped %q from the %s history, it was %w", op.Name, name, ErrStale)
+}
+
+func appendHistory(tx store.Tx, changes []db.Change, t time.Time) error {
+	events, err := history.Events(tx, changes, t)
+	if err != nil {
+		return err
+	}
+	return tx.AppendEvents(events)
+}
+
+// expect fails with ErrStale if the entity is not in the state the operation
+// left it in, applying it would overwrite what was changed since
+func expect(tx store.Tx, entity, id string, state json.RawMessage) error {
+	var now any
+	var err error
+	switch entity {
+	case db.EntityPerson:
+		now, err = tx.GetPerson(id)
+	case db.EntityRelation:
+		now, err = tx.GetRelation(id)
+	case db.EntityField:
+		now, err = tx.GetField(id)
+	case db.EntityInteraction:
+		now, err = tx.GetInteraction(id)
+	case db.EntityReminder:
+		now, err = tx.GetReminder(id)
+	default:
+		return fmt.Errorf("unknown entity %q", entity)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		if len(state) == 0 {
+			return nil
+		}
+		return ErrStale
+	}
+	if err != nil {
+		return err
+	}
+	if len(state) == 0 {
+		return ErrStale
+	}
+	var want any
+	if err := json.Unmarshal(state, &want); err != nil {
+		return err
+	}
+	raw, err := json.Marshal(now)
+	if err != nil {
+		return err
+	}
+	var got any
+	if err := json.Unmarshal(raw, &got); err != nil {
+		return err
+	}
+	if !same(got, want) {
+		return ErrStale
+	}
+	return nil
+}
+
+// same compares two decoded json values. Stores differ in how they give back
+// nothing, so null, "", [] and {} are all the same.
+func same(a, b any) bool {
+	if blank(a) || blank(b) {
+		return blank(a) && blank(b)
+	}
+	switch a := a.(type) {
+	case map[string]any:
+		b, ok := b.(map[string]any)
+		if !ok {
+			return false
+		}
+		for k, v := range a {
+			if !same(v, b[k]) {
+				return false
+			}
+		}
+		for k, v := range b {
+			if _, ok := a[k]; !ok && !blank(v) {
+				return false
+			}
+		}
+		return true
+	case []any:
+		b, ok := b.([]any)
+		if !ok || len(a) != len(b) {
+			return false
+		}
+		for i := range a {
+			if !same(a[i], b[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return a == b
+}
+
+func blank(v any) bool {
+	switch v := v.(type) {
+	case nil:
+		return true
+	case string:
+		return v == ""
+	case []any:
+		return len(v) == 0
+	case map[string]any:
+		return len(v) == 0
+	}
+	return false
+}
+
+// apply moves an entity from state "from" to state "to".
+// An empty state means the entity does not exist.
+func apply(tx store.Tx, entity, id string, from, to json.RawMessage) error {
+	switch entity {
+	case db.EntityPerson:
+		if len(to) == 0 {
+			return tx.DeletePerson(id)
+		}
+		var p person.Person
+		if err := json.Unmarshal(to, &p); err != nil {
+			return err
+		}
+		if len(from) == 0 {
+			return tx.CreatePerson(p)
+		}
+		return tx.UpdatePerson(p)
+
+	case db.EntityRelation:
+		if len(to) == 0 {
+			return tx.DeleteRelation(id)
+		}
+		var r relation.Relation
+		if err := json.Unmarshal(to, &r); err != nil {
+			return err
+		}
+		if len(from) == 0 {
+			return tx.CreateRelation(r)
+		}
+		return tx.UpdateRelation(r)
+
+	case db.EntityField:
+		if len(to) == 0 {
+			return tx.DeleteField(id)
+		}
+		var f db.FieldDef
+		if err := json.Unmarshal(to, &f); err != nil {
+			return err
+		}
+		if len(from) == 0 {
+			return tx.CreateField(f)
+		}
+		return tx.UpdateField(f)
+
+	case db.EntityInteraction:
+		if len(to) == 0 {
+			return tx.DeleteInteraction(id)
+		}
+		var in interaction.Interaction
+		if err := json.Unmarshal(to, &in); err != nil {
+			return err
+		}
+		if len(from) == 0 {
+			return tx.CreateInteraction(in)
+		}
+		return tx.UpdateInteraction(in)
+
+	case db.EntityReminder:
+		if len(to) == 0 {
+			return tx.DeleteReminder(id)
+		}
+		var rem reminder.Reminder
+		if err := json.Unmarshal(to, &rem); err != nil {
+			return err
+		}
+		if len(from) == 0 {
+			return tx.CreateReminder(rem)
+		}
+		return tx.UpdateReminder(rem)
+	}
+	return fmt.Errorf("unknown entity %q", entity)
+}
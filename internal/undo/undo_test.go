@@ -0,0 +1,232 @@
+package undo
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/N3moAhead/connect3/internal/config"
+	"github.com/N3moAhead/connect3/internal/db"
+	"github.com/N3moAhead/connect3/internal/person"
+	"github.com/N3moAhead/connect3/internal/relation"
+	"github.com/N3moAhead/connect3/internal/reminder"
+	"github.com/N3moAhead/connect3/internal/store"
+)
+
+func mustDo(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func name(t *testing.T, st store.Store, id string) string {
+	t.Helper()
+	p, err := st.GetPerson(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return ""
+	}
+	mustDo(t, err)
+	return p.Name
+}
+
+func create(st store.Store, p person.Person) error {
+	return Do(st, "Create "+p.Name, func(tx store.Tx) error { return tx.CreatePerson(p) })
+}
+
+func rename(st store.Store, id, to string) error {
+	return Do(st, "Rename to "+to, func(tx store.Tx) error {
+		p, err := tx.GetPerson(id)
+		if err != nil {
+			return err
+		}
+		p.Name = to
+		return tx.UpdatePerson(p)
+	})
+}
+
+func TestUndoRedo(t *testing.T) {
+	st := store.NewMemory(db.Database{})
+	mustDo(t, create(st, person.Person{ID: "p1", Name: "Ada"}))
+	mustDo(t, rename(st, "p1", "Ada Lovelace"))
+
+	op, err := Undo(st)
+	mustDo(t, err)
+	if op.Name != "Rename to Ada Lovelace" || name(t, st, "p1") != "Ada" {
+		t.Fatalf("undid %q, name is %q", op.Name, name(t, st, "p1"))
+	}
+	_, err = Undo(st)
+	mustDo(t, err)
+	if name(t, st, "p1") != "" {
+		t.Fatal("undoing the create should remove the person")
+	}
+	if _, err := Undo(st); !errors.Is(err, ErrNothingToUndo) {
+		t.Fatalf("got %v, want ErrNothingToUndo", err)
+	}
+
+	_, err = Redo(st)
+	mustDo(t, err)
+	_, err = Redo(st)
+	mustDo(t, err)
+	if name(t, st, "p1") != "Ada Lovelace" {
+		t.Fatalf("after redo the name is %q", name(t, st, "p1"))
+	}
+	if _, err := Redo(st); !errors.Is(err, ErrNothingToRedo) {
+		t.Fatalf("got %v, want ErrNothingToRedo", err)
+	}
+}
+
+func TestNewChangeClearsRedo(t *testing.T) {
+	st := store.NewMemory(db.Database{})
+	mustDo(t, create(st, person.Person{ID: "p1", Name: "Ada"}))
+	mustDo(t, rename(st, "p1", "Ada Lovelace"))
+	_, err := Undo(st)
+	mustDo(t, err)
+	mustDo(t, rename(st, "p1", "Countess"))
+
+	if _, err := Redo(st); !errors.Is(err, ErrNothingToRedo) {
+		t.Fatalf("got %v, want ErrNothingToRedo", err)
+	}
+}
+
+func TestUndoDeleteBringsBackRelationsAndReminders(t *testing.T) {
+	st := store.NewMemory(db.Database{})
+	mustDo(t, Do(st, "Setup", func(tx store.Tx) error {
+		if err := tx.CreatePerson(person.Person{ID: "p1", Name: "Ada"}); err != nil {
+			return err
+		}
+		if err := tx.CreatePerson(person.Person{ID: "p2", Name: "Grace"}); err != nil {
+			return err
+		}
+		if err := tx.CreateRelation(relation.Relation{ID: "r1", FromID: "p1", ToID: "p2", Strength: 4}); err != nil {
+			return err
+		}
+		return tx.CreateReminder(reminder.Reminder{ID: "m1", PersonID: "p1", Date: "2024-12-10", Text: "Card"})
+	}))
+	mustDo(t, Do(st, "Delete Ada", func(tx store.Tx) error { return tx.DeletePerson("p1") }))
+	if _, err := st.GetRelation("r1"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("got %v, the relation should be gone with Ada", err)
+	}
+
+	_, err := Undo(st)
+	mustDo(t, err)
+	if name(t, st, "p1") != "Ada" {
+		t.Fatal("Ada is not back")
+	}
+	if _, err := st.GetRelation("r1"); err != nil {
+		t.Fatalf("relation: %v", err)
+	}
+	if _, err := st.GetReminder("m1"); err != nil {
+		t.Fatalf("reminder: %v", err)
+	}
+}
+
+func TestStaleOperationIsDropped(t *testing.T) {
+	st := store.NewMemory(db.Database{})
+	mustDo(t, create(st, person.Person{ID: "p2", Name: "Grace"}))
+	mustDo(t, create(st, person.Person{ID: "p1", Name: "Ada"}))
+	mustDo(t, rename(st, "p1", "Ada Lovelace"))
+	// Someone else deletes Ada, the rename can not be undone anymore
+	mustDo(t, st.DeletePerson("p1"))
+
+	if _, err := Undo(st); !errors.Is(err, ErrStale) {
+		t.Fatalf("got %v, want ErrStale", err)
+	}
+	// The stale rename and create are gone, what is below them still works
+	if _, err := Undo(st); !errors.Is(err, ErrStale) {
+		t.Fatalf("got %v, want ErrStale", err)
+	}
+	op, err := Undo(st)
+	mustDo(t, err)
+	if op.Name != "Create Grace" || name(t, st, "p2") != "" {
+		t.Fatalf("undid %q, Grace is %q", op.Name, name(t, st, "p2"))
+	}
+}
+
+func TestUndoLimit(t *testing.T) {
+	st := store.NewMemory(db.Database{})
+	mustDo(t, create(st, person.Person{ID: "p1", Name: "Ada"}))
+	for range config.UNDO_LIMIT + 5 {
+		mustDo(t, rename(st, "p1", "Ada"))
+	}
+	log, err := st.UndoLog()
+	mustDo(t, err)
+	if len(log.Undo) != config.UNDO_LIMIT {
+		t.Fatalf("got %d operations, want %d", len(log.Undo), config.UNDO_LIMIT)
+	}
+}
+
+func TestUndoKeepsChangesMadeElsewhere(t *testing.T) {
+	st := store.NewMemory(db.Database{})
+	mustDo(t, create(st, person.Person{ID: "p1", Name: "Ada"}))
+	mustDo(t, rename(st, "p1", "Ada Lovelace"))
+	// Someone else renames Ada again, outside of the undo history
+	p, err := st.GetPerson("p1")
+	mustDo(t, err)
+	p.Name = "Countess"
+	mustDo(t, st.UpdatePerson(p))
+
+	if _, err := Undo(st); !errors.Is(err, ErrStale) {
+		t.Fatalf("got %v, want ErrStale", err)
+	}
+	if name(t, st, "p1") != "Countess" {
+		t.Fatalf("the rename made elsewhere was lost, the name is %q", name(t, st, "p1"))
+	}
+}
+
+func TestRedoCreateOfTakenID(t *testing.T) {
+	st := store.NewMemory(db.Database{})
+	mustDo(t, create(st, person.Person{ID: "p1", Name: "Ada"}))
+	_, err := Undo(st)
+	mustDo(t, err)
+	mustDo(t, st.CreatePerson(person.Person{ID: "p1", Name: "Grace"}))
+
+	if _, err := Redo(st); !errors.Is(err, ErrStale) {
+		t.Fatalf("got %v, want ErrStale", err)
+	}
+	if name(t, st, "p1") != "Grace" {
+		t.Fatalf("the person made elsewhere was replaced, the name is %q", name(t, st, "p1"))
+	}
+	if _, err := Redo(st); !errors.Is(err, ErrNothingToRedo) {
+		t.Fatalf("got %v, the stale redo should be gone", err)
+	}
+}
+
+func TestUndoOnSQLite(t *testing.T) {
+	// sqlite gives back empty lists where the recorded person had none,
+	// that is no change made elsewhere
+	st, err := store.OpenSQLite(filepath.Join(t.TempDir(), "data.db"))
+	mustDo(t, err)
+	defer st.Close()
+	mustDo(t, create(st, person.Person{ID: "p1", Name: "Ada"}))
+	mustDo(t, rename(st, "p1", "Ada Lovelace"))
+
+	_, err = Undo(st)
+	mustDo(t, err)
+	_, err = Undo(st)
+	mustDo(t, err)
+	_, err = Redo(st)
+	mustDo(t, err)
+	if name(t, st, "p1") != "Ada" {
+		t.Fatalf("the name is %q", name(t, st, "p1"))
+	}
+}
+
+func TestDoRecordsHistory(t *testing.T) {
+	st := store.NewMemory(db.Database{})
+	mustDo(t, create(st, person.Person{ID: "p1", Name: "Ada"}))
+	mustDo(t, rename(st, "p1", "Ada Lovelace"))
+	mustDo(t, Do(st, "Delete Ada", func(tx store.Tx) error { return tx.DeletePerson("p1") }))
+
+	events, err := st.ListEvents("p1")
+	mustDo(t, err)
+	got := []string{}
+	for _, e := range events {
+		got = append(got, e.Action+" "+e.Field+" "+e.Label)
+	}
+	want := []string{"create  Ada", "update name Ada Lovelace", "delete  Ada Lovelace"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}